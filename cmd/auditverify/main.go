@@ -0,0 +1,42 @@
+// Command auditverify checks the audit log hash chain and reports every
+// missing, reordered or edited entry. It exits with status 1 if it finds
+// any.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/audit"
+)
+
+func main() {
+	// The database settings may come from the environment alone.
+	_ = godotenv.Load()
+
+	db := app.SetupDB()
+	defer db.Close()
+
+	problems, head, err := audit.Verify(db)
+	if err != nil {
+		log.Fatal("Verification failed: ", err)
+	}
+
+	for _, p := range problems {
+		fmt.Printf("seq %d: %s\n", p.Seq, p.Message)
+	}
+	if head == nil {
+		fmt.Println("Audit log is empty")
+	} else {
+		fmt.Printf("Head: seq %d hash %s\n", head.Seq, head.Hash)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("Audit log is intact")
+}
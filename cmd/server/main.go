@@ -16,12 +16,17 @@ import (
 
 	_ "github.com/nihsioK/go-kanban/docs"
 	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/project"
+	"github.com/nihsioK/go-kanban/internal/realtime"
 	"github.com/nihsioK/go-kanban/internal/routes"
 )
 
 func main() {
 	a := app.Initialize()
-	router := routes.SetupRouter(a)
+	hub := realtime.NewHub(a.DB)
+	router := routes.SetupRouter(a, hub)
+	project.StartTrashPurger(a)
+	realtime.StartListener(hub)
 
 	log.Println("Server listening on :8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
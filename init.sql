@@ -8,6 +8,46 @@ BEGIN
 END;
 $$;
 
+-- Create the function that keeps the audit log append-only
+CREATE OR REPLACE FUNCTION reject_audit_log_change() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$;
+
+-- Create the function that publishes activity events to live clients. The
+-- changes themselves can outgrow the NOTIFY payload limit, so only the names
+-- of the changed fields are sent.
+CREATE OR REPLACE FUNCTION notify_activity_event() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    PERFORM pg_notify('activity_events', json_build_object(
+        'id', NEW.id,
+        'project', NEW.project_id,
+        'actor', NEW.actor_id,
+        'action', NEW.action,
+        'entity_type', NEW.entity_type,
+        'entity_id', NEW.entity_id,
+        'fields', (SELECT coalesce(json_agg(k ORDER BY k), '[]'::json) FROM jsonb_object_keys(NEW.changes) AS k),
+        'created_at', NEW.created_at
+    )::text);
+    RETURN NEW;
+END;
+$$;
+
+-- Create the version function used for optimistic concurrency
+CREATE OR REPLACE FUNCTION bump_version_column() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$;
+
 -- Create users table
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
@@ -29,6 +69,118 @@ CREATE TABLE IF NOT EXISTS projects (
     dev_dependencies TEXT[],
     status VARCHAR(50) DEFAULT 'active',
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    archived_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+
+-- Bring projects tables created by earlier versions up to date
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Create project templates table
+CREATE TABLE IF NOT EXISTS project_templates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    fields JSONB NOT NULL DEFAULT '{}',
+    shared BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create project ownership transfers table
+CREATE TABLE IF NOT EXISTS project_transfers (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    from_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMPTZ
+);
+
+-- Create tags tables
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#cccccc',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS project_tags (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (project_id, tag_id)
+);
+
+-- Create attachments tables
+CREATE TABLE IF NOT EXISTS blobs (
+    sha256 CHAR(64) PRIMARY KEY,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL REFERENCES blobs(sha256),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create activity events table
+CREATE TABLE IF NOT EXISTS activity_events (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    actor_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create audit log table. Entries are hash-chained by the application and
+-- the table only accepts inserts.
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq BIGINT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    actor_id VARCHAR(20) NOT NULL DEFAULT '',
+    actor_username VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL DEFAULT '',
+    target_id VARCHAR(50) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    details JSON NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+-- Create saved views table
+CREATE TABLE IF NOT EXISTS saved_views (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    shared BOOLEAN NOT NULL DEFAULT false,
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
@@ -38,6 +190,25 @@ CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
 CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id);
 CREATE INDEX IF NOT EXISTS idx_projects_status ON projects(status);
 CREATE INDEX IF NOT EXISTS idx_projects_created_at ON projects(created_at);
+CREATE INDEX IF NOT EXISTS idx_projects_user_created_id ON projects(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_projects_dependencies ON projects USING GIN (dependencies);
+CREATE INDEX IF NOT EXISTS idx_projects_dev_dependencies ON projects USING GIN (dev_dependencies);
+CREATE INDEX IF NOT EXISTS idx_projects_search_vector ON projects USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON projects(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_project_templates_user_id ON project_templates(user_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_user_id ON saved_views(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, lower(name));
+CREATE INDEX IF NOT EXISTS idx_project_tags_tag_id ON project_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_project_id ON attachments(project_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments(sha256);
+CREATE INDEX IF NOT EXISTS idx_activity_events_project_id ON activity_events(project_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_events_actor_id ON activity_events(actor_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, seq DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, seq DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id, seq DESC);
+CREATE INDEX IF NOT EXISTS idx_project_transfers_project_id ON project_transfers(project_id);
+CREATE INDEX IF NOT EXISTS idx_project_transfers_to_user_id ON project_transfers(to_user_id) WHERE status = 'pending';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_project_transfers_one_pending ON project_transfers(project_id) WHERE status = 'pending';
 
 -- Create triggers
 DROP TRIGGER IF EXISTS update_users_updated_at ON users;
@@ -49,3 +220,38 @@ DROP TRIGGER IF EXISTS update_projects_updated_at ON projects;
 CREATE TRIGGER update_projects_updated_at 
     BEFORE UPDATE ON projects 
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS bump_projects_version ON projects;
+CREATE TRIGGER bump_projects_version 
+    BEFORE UPDATE ON projects 
+    FOR EACH ROW EXECUTE FUNCTION bump_version_column();
+
+DROP TRIGGER IF EXISTS update_project_templates_updated_at ON project_templates;
+CREATE TRIGGER update_project_templates_updated_at 
+    BEFORE UPDATE ON project_templates 
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_tags_updated_at ON tags;
+CREATE TRIGGER update_tags_updated_at 
+    BEFORE UPDATE ON tags 
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_saved_views_updated_at ON saved_views;
+CREATE TRIGGER update_saved_views_updated_at 
+    BEFORE UPDATE ON saved_views 
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS notify_activity_events ON activity_events;
+CREATE TRIGGER notify_activity_events
+    AFTER INSERT ON activity_events
+    FOR EACH ROW EXECUTE FUNCTION notify_activity_event();
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_log_change();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_log_change();
@@ -0,0 +1,115 @@
+package activity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// GetProjectActivity godoc
+// @Summary List a project's activity
+// @Description Retrieve a page of the changes made to a project owned by the authenticated user, newest first. Follow next_cursor (or the Link header) for the next page.
+// @Tags activity
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Success 200 {object} EventPage
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/activity [get]
+func GetProjectActivity(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		limit, cursor, ok := parsePage(w, r)
+		if !ok {
+			return
+		}
+
+		// Trashed projects keep their history until they are purged.
+		var owner string
+		err := a.DB.QueryRow("SELECT user_id FROM projects WHERE id=$1", projectID).Scan(&owner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
+			return
+		}
+
+		events, next, err := listEvents(a.DB, "e.project_id=$1", limit, cursor, projectID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch activity")
+			return
+		}
+
+		app.SetNextLink(w, r, next)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(EventPage{Data: events, NextCursor: next})
+	}
+}
+
+// GetFeed godoc
+// @Summary Get the personal activity feed
+// @Description Retrieve a page of the changes made to the authenticated user's projects and the changes the user made elsewhere, newest first
+// @Tags activity
+// @Accept json
+// @Produce json
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Success 200 {object} EventPage
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /me/feed [get]
+func GetFeed(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		limit, cursor, ok := parsePage(w, r)
+		if !ok {
+			return
+		}
+
+		events, next, err := listEvents(a.DB,
+			"(e.actor_id=$1 OR e.project_id IN (SELECT id FROM projects WHERE user_id=$1))",
+			limit, cursor, claims.ID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch activity")
+			return
+		}
+
+		app.SetNextLink(w, r, next)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(EventPage{Data: events, NextCursor: next})
+	}
+}
+
+func parsePage(w http.ResponseWriter, r *http.Request) (int, *app.Cursor, bool) {
+	q := r.URL.Query()
+	limit, err := app.ParseLimit(q)
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return 0, nil, false
+	}
+	cursor, err := app.DecodeCursor(q.Get("cursor"))
+	if err != nil || (cursor != nil && cursor.Sort != "id") {
+		app.RespondWithError(w, http.StatusBadRequest, "invalid cursor")
+		return 0, nil, false
+	}
+	return limit, cursor, true
+}
@@ -0,0 +1,43 @@
+package activity
+
+import "time"
+
+const (
+	ActionCreated     = "created"
+	ActionUpdated     = "updated"
+	ActionDeleted     = "deleted"
+	ActionRestored    = "restored"
+	ActionArchived    = "archived"
+	ActionUnarchived  = "unarchived"
+	ActionTransferred = "transferred"
+	ActionTagged      = "tagged"
+	ActionUntagged    = "untagged"
+
+	EntityProject = "project"
+	EntityTag     = "tag"
+)
+
+// Event records one change to an entity of a project: who made it, what
+// kind of change it was and, for edits, the old and new value of every
+// field it touched.
+type Event struct {
+	ID            string            `json:"id"`
+	ProjectID     string            `json:"project"`
+	ActorID       string            `json:"actor"`
+	ActorUsername string            `json:"actor_username"`
+	Action        string            `json:"action"`
+	EntityType    string            `json:"entity_type"`
+	EntityID      string            `json:"entity_id"`
+	Changes       map[string]Change `json:"changes,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type EventPage struct {
+	Data       []Event `json:"data"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,85 @@
+package activity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+const eventSelect = `SELECT e.id, e.project_id, e.actor_id, u.username, e.action,
+		e.entity_type, e.entity_id, e.changes, e.created_at
+	FROM activity_events e
+	JOIN users u ON u.id = e.actor_id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEvent(s scanner, e *Event) error {
+	var changes []byte
+	if err := s.Scan(&e.ID, &e.ProjectID, &e.ActorID, &e.ActorUsername, &e.Action,
+		&e.EntityType, &e.EntityID, &changes, &e.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(changes, &e.Changes)
+}
+
+// Record stores an event. The change it describes has already been
+// committed, so a failure here is logged rather than reported to the
+// client.
+func Record(db *sql.DB, e Event) {
+	if e.Changes == nil {
+		e.Changes = map[string]Change{}
+	}
+	changes, err := json.Marshal(e.Changes)
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO activity_events
+			(project_id, actor_id, action, entity_type, entity_id, changes)
+			VALUES ($1,$2,$3,$4,$5,$6)`,
+			e.ProjectID, e.ActorID, e.Action, e.EntityType, e.EntityID, changes)
+	}
+	if err != nil {
+		log.Printf("Recording %s %s %s failed: %v", e.EntityType, e.EntityID, e.Action, err)
+	}
+}
+
+// listEvents returns a page of the events matching where, newest first.
+// where may refer to the arguments as $1.. and must leave the rest of the
+// placeholders to listEvents.
+func listEvents(db *sql.DB, where string, limit int, cursor *app.Cursor, args ...interface{}) ([]Event, string, error) {
+	query := eventSelect + ` WHERE ` + where
+	if cursor != nil {
+		args = append(args, cursor.ID)
+		query += ` AND e.id < $` + strconv.Itoa(len(args)) + `::bigint`
+	}
+	args = append(args, limit+1)
+	query += ` ORDER BY e.id DESC LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := scanEvent(rows, &e); err != nil {
+			return nil, "", err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(events) > limit {
+		events = events[:limit]
+		next = app.Cursor{Sort: "id", ID: events[len(events)-1].ID}.Encode()
+	}
+	return events, next, nil
+}
@@ -1,19 +1,34 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/nihsioK/go-kanban/internal/storage"
 )
 
 type App struct {
-	DB      *sql.DB
-	JWTKey  []byte
-	Schemas map[string]string
+	DB             *sql.DB
+	JWTKey         []byte
+	Schemas        map[string]string
+	PasswordPolicy PasswordPolicy
+	RequireIfMatch bool
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	Storage            storage.Store
+	AttachmentMaxBytes int64
+	AttachmentTypes    []string
+
+	AdminUsers []string
 }
 
 func Initialize() *App {
@@ -24,17 +39,37 @@ func Initialize() *App {
 	db := SetupDB()
 	schemas := loadSchemas()
 	jwtKey := []byte(os.Getenv("JWT_SECRET"))
+	passwordPolicy := loadPasswordPolicy()
+	requireIfMatch := envBool("REQUIRE_IF_MATCH", false)
+	trashRetention := envDuration("TRASH_RETENTION", 30*24*time.Hour)
+	trashPurgeInterval := envDuration("TRASH_PURGE_INTERVAL", time.Hour)
+	store := loadStorage()
+	attachmentMaxBytes := int64(envInt("ATTACHMENT_MAX_BYTES", 25<<20))
+	attachmentTypes := envList("ATTACHMENT_ALLOWED_TYPES")
+	adminUsers := envList("ADMIN_USERS")
 
 	return &App{
-		DB:      db,
-		JWTKey:  jwtKey,
-		Schemas: schemas,
+		DB:             db,
+		JWTKey:         jwtKey,
+		Schemas:        schemas,
+		PasswordPolicy: passwordPolicy,
+		RequireIfMatch: requireIfMatch,
+
+		TrashRetention:     trashRetention,
+		TrashPurgeInterval: trashPurgeInterval,
+
+		Storage:            store,
+		AttachmentMaxBytes: attachmentMaxBytes,
+		AttachmentTypes:    attachmentTypes,
+
+		AdminUsers: adminUsers,
 	}
 
 }
 
-func SetupDB() *sql.DB {
-	connStr := fmt.Sprintf(
+// ConnString builds the Postgres connection string from the DB* variables.
+func ConnString() string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		os.Getenv("DBHOST"),
 		os.Getenv("DBPORT"),
@@ -42,8 +77,10 @@ func SetupDB() *sql.DB {
 		os.Getenv("DBPASSWORD"),
 		os.Getenv("DBNAME"),
 	)
+}
 
-	db, err := sql.Open("postgres", connStr)
+func SetupDB() *sql.DB {
+	db, err := sql.Open("postgres", ConnString())
 
 	if err != nil {
 		log.Fatal("Database connection error:", err)
@@ -60,3 +97,96 @@ func SetupDB() *sql.DB {
 	log.Println("Connected to PostgreSQL!")
 	return db
 }
+
+// WithTx runs fn in a transaction, committing it if fn succeeds and rolling
+// it back otherwise.
+func WithTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadStorage() storage.Store {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "data/attachments"
+		}
+		store, err := storage.NewLocal(dir)
+		if err != nil {
+			log.Fatal("Local storage setup failed:", err)
+		}
+		return store
+	case "s3":
+		store, err := storage.NewS3(context.Background(), storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    envBool("S3_USE_SSL", true),
+		})
+		if err != nil {
+			log.Fatal("S3 storage setup failed:", err)
+		}
+		log.Println("Connected to S3 storage!")
+		return store
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q", backend)
+		return nil
+	}
+}
+
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return n
+}
+
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return b
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid value for %s: %q", key, v)
+	}
+	return d
+}
+
+func envList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag formats a row version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagListed reports whether an If-Match or If-None-Match header lists etag,
+// or is "*". Weak tags only match when weak is set, as If-None-Match allows.
+func etagListed(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatch evaluates the If-Match precondition of a write against the
+// current entity tag. When it fails it writes a 412, or a 428 if the header is
+// missing and RequireIfMatch is set, and returns false.
+func (a *App) CheckIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if a.RequireIfMatch {
+			RespondWithError(w, http.StatusPreconditionRequired, "If-Match header required")
+			return false
+		}
+		return true
+	}
+	if !etagListed(header, etag, false) {
+		RespondWithError(w, http.StatusPreconditionFailed, "Resource has been modified")
+		return false
+	}
+	return true
+}
+
+// NotModified reports whether the If-None-Match header of a read lists etag,
+// in which case it has already written a 304.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagListed(header, etag, true) {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ConditionalVersion returns the version a write must still find in the row
+// for the If-Match header to hold, or 0 when the write is unconditional.
+func ConditionalVersion(r *http.Request, version int) int {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0
+	}
+	return version
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -21,11 +25,24 @@ type Claims struct {
 
 func (a *App) Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s\n", r.RemoteAddr, r.Method, r.URL)
+		log.Printf("%s %s %s\n", r.RemoteAddr, r.Method, redactURL(r.URL))
 		next.ServeHTTP(w, r)
 	})
 }
 
+// redactURL hides the token query parameter, which browsers use to
+// authenticate WebSockets, so that credentials never reach the log.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if !q.Has("token") {
+		return u.String()
+	}
+	q.Set("token", "REDACTED")
+	redacted := *u
+	redacted.RawQuery = q.Encode()
+	return redacted.String()
+}
+
 func (a *App) JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -34,14 +51,8 @@ func (a *App) JWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-			return a.JWTKey, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := a.ParseToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
 			RespondWithError(w, http.StatusUnauthorized, "Invalid token")
 			return
 		}
@@ -51,9 +62,36 @@ func (a *App) JWTAuth(next http.Handler) http.Handler {
 	})
 }
 
+// ParseToken verifies a JWT issued at login and returns its claims.
+func (a *App) ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.JWTKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// RequireAdmin only lets through users listed in ADMIN_USERS. It must run
+// after JWTAuth.
+func (a *App) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*Claims)
+		if !slices.Contains(a.AdminUsers, claims.Username) {
+			RespondWithError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (a *App) Validate(schemaKey string, next http.Handler) http.Handler {
-	schema, ok := a.Schemas[schemaKey]
-	if !ok {
+	if _, ok := a.Schemas[schemaKey]; !ok {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusInternalServerError, "Schema not found")
 		})
@@ -67,19 +105,12 @@ func (a *App) Validate(schemaKey string, next http.Handler) http.Handler {
 			return
 		}
 
-		schemaLoader := gojsonschema.NewStringLoader(schema)
-		documentLoader := gojsonschema.NewGoLoader(body)
-
-		result, err := gojsonschema.Validate(schemaLoader, documentLoader)
+		errs, err := a.ValidateDocument(schemaKey, body)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, "Schema validation error")
 			return
 		}
-		if !result.Valid() {
-			var errs []string
-			for _, e := range result.Errors() {
-				errs = append(errs, e.String())
-			}
+		if len(errs) > 0 {
 			RespondWithError(w, http.StatusBadRequest, strings.Join(errs, ", "))
 			return
 		}
@@ -88,3 +119,26 @@ func (a *App) Validate(schemaKey string, next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// ValidateDocument checks an already decoded JSON document against one of the
+// loaded schemas and returns a message per violation.
+func (a *App) ValidateDocument(schemaKey string, doc interface{}) ([]string, error) {
+	schema, ok := a.Schemas[schemaKey]
+	if !ok {
+		return nil, fmt.Errorf("schema %q not found", schemaKey)
+	}
+
+	schemaLoader := gojsonschema.NewStringLoader(schema)
+	documentLoader := gojsonschema.NewGoLoader(doc)
+
+	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	for _, e := range result.Errors() {
+		errs = append(errs, e.String())
+	}
+	return errs, nil
+}
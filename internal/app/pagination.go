@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Cursor marks the last row of a page for keyset pagination: the value of the
+// column the page is sorted by and the row id as a tie-breaker.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	// The id ends up compared with an integer column, so anything else would
+	// only fail once it reaches the database.
+	if id, err := strconv.ParseInt(c.ID, 10, 64); err != nil || id < 1 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// ParseLimit reads the "limit" query parameter, falling back to
+// DefaultPageLimit and rejecting anything outside 1..MaxPageLimit.
+func ParseLimit(q url.Values) (int, error) {
+	v := q.Get("limit")
+	if v == "" {
+		return DefaultPageLimit, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > MaxPageLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+	return n, nil
+}
+
+// SetNextLink adds an RFC 8288 Link header pointing at the page after the
+// current one, keeping every other query parameter of the request.
+func SetNextLink(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", next)
+	u.RawQuery = q.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
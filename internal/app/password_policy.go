@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+)
+
+type PasswordPolicy struct {
+	MinLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowUsername bool
+	Breached         *BreachedList
+}
+
+func loadPasswordPolicy() PasswordPolicy {
+	policy := PasswordPolicy{
+		MinLength:        envInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:     envBool("PASSWORD_REQUIRE_UPPER", false),
+		RequireLower:     envBool("PASSWORD_REQUIRE_LOWER", false),
+		RequireDigit:     envBool("PASSWORD_REQUIRE_DIGIT", false),
+		RequireSymbol:    envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		DisallowUsername: envBool("PASSWORD_DISALLOW_USERNAME", true),
+	}
+
+	if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			log.Fatalf("Failed to open breached password list %s: %v", path, err)
+		}
+		policy.Breached = &BreachedList{Path: path}
+	}
+
+	return policy
+}
+
+// Check returns one detail per rule the password violates, or nil if it
+// satisfies the policy.
+func (p PasswordPolicy) Check(username, password string) ([]ErrorDetail, error) {
+	var details []ErrorDetail
+
+	if len([]rune(password)) < p.MinLength {
+		details = append(details, ErrorDetail{
+			Rule:    "min_length",
+			Message: fmt.Sprintf("Password must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		details = append(details, ErrorDetail{Rule: "require_upper", Message: "Password must contain an uppercase letter"})
+	}
+	if p.RequireLower && !hasLower {
+		details = append(details, ErrorDetail{Rule: "require_lower", Message: "Password must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !hasDigit {
+		details = append(details, ErrorDetail{Rule: "require_digit", Message: "Password must contain a digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		details = append(details, ErrorDetail{Rule: "require_symbol", Message: "Password must contain a symbol"})
+	}
+
+	if p.DisallowUsername && username != "" &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		details = append(details, ErrorDetail{Rule: "no_username", Message: "Password must not contain the username"})
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			details = append(details, ErrorDetail{Rule: "breached", Message: "Password appears in a list of breached passwords"})
+		}
+	}
+
+	return details, nil
+}
+
+// BreachedList is a file of uppercase hex SHA-1 hashes, one per line and
+// sorted, optionally followed by ":count" as in the Have I Been Pwned dumps.
+// Lookups binary search the file on disk so it never has to fit in memory.
+type BreachedList struct {
+	Path string
+}
+
+func (b *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// lo is always the start of a line and every line before it sorts below
+	// target; every line starting at or after hi sorts at or above it.
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := lineStart(f, mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		key, next, err := readKey(f, start)
+		if err != nil {
+			return false, err
+		}
+		if key < target {
+			lo = next
+		} else {
+			hi = start
+		}
+	}
+
+	if lo >= info.Size() {
+		return false, nil
+	}
+	key, _, err := readKey(f, lo)
+	if err != nil {
+		return false, err
+	}
+	return key == target, nil
+}
+
+// lineStart returns the offset of the first line beginning at or after off.
+func lineStart(f *os.File, off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, off-1, 1<<62))
+	skipped, err := r.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return off - 1 + int64(len(skipped)), nil
+}
+
+// readKey returns the hash on the line starting at off and the offset of the
+// following line.
+func readKey(f *os.File, off int64) (string, int64, error) {
+	r := bufio.NewReader(io.NewSectionReader(f, off, 1<<62))
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	next := off + int64(len(line))
+	key, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(key), next, nil
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func hashPassword(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeBreachedList writes the hashes of passwords as a sorted list in the
+// Have I Been Pwned format and returns its path.
+func writeBreachedList(t *testing.T, passwords ...string) string {
+	t.Helper()
+	var lines []string
+	for _, p := range passwords {
+		lines = append(lines, hashPassword(p)+":3")
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBreachedListContains(t *testing.T) {
+	listed := []string{"password", "123456", "qwerty", "letmein", "dragon", "monkey", "trustno1"}
+	list := &BreachedList{Path: writeBreachedList(t, listed...)}
+
+	var hashes []string
+	byHash := map[string]string{}
+	for _, p := range listed {
+		hashes = append(hashes, hashPassword(p))
+		byHash[hashPassword(p)] = p
+	}
+	sort.Strings(hashes)
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"first entry", byHash[hashes[0]], true},
+		{"last entry", byHash[hashes[len(hashes)-1]], true},
+		{"middle entry", byHash[hashes[len(hashes)/2]], true},
+		{"missing entry", "correct horse battery staple", false},
+		{"empty password", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := list.Contains(tt.password)
+			if err != nil {
+				t.Fatalf("Contains: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedListSingleLineWithoutNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.ToLower(hashPassword("hunter2"))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	list := &BreachedList{Path: path}
+
+	for password, want := range map[string]bool{"hunter2": true, "hunter3": false} {
+		got, err := list.Contains(password)
+		if err != nil {
+			t.Fatalf("Contains: %v", err)
+		}
+		if got != want {
+			t.Errorf("Contains(%q) = %v, want %v", password, got, want)
+		}
+	}
+}
+
+func TestBreachedListEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := (&BreachedList{Path: path}).Contains("password")
+	if err != nil {
+		t.Fatalf("Contains: %v", err)
+	}
+	if got {
+		t.Error("empty list reported a match")
+	}
+}
+
+func rules(details []ErrorDetail) []string {
+	names := []string{}
+	for _, d := range details {
+		names = append(names, d.Rule)
+	}
+	return names
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	strict := PasswordPolicy{
+		MinLength:        8,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowUsername: true,
+	}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		username string
+		password string
+		want     []string
+	}{
+		{"one below min length", PasswordPolicy{MinLength: 8}, "", "abcdefg", []string{"min_length"}},
+		{"exactly min length", PasswordPolicy{MinLength: 8}, "", "abcdefgh", []string{}},
+		{"length counts characters not bytes", PasswordPolicy{MinLength: 4}, "", "ääää", []string{}},
+		{"empty password", PasswordPolicy{MinLength: 1}, "", "", []string{"min_length"}},
+		{"satisfies every rule", strict, "alice", "Secr3t!pw", []string{}},
+		{"missing upper", strict, "alice", "secr3t!pw", []string{"require_upper"}},
+		{"missing lower", strict, "alice", "SECR3T!PW", []string{"require_lower"}},
+		{"missing digit", strict, "alice", "Secret!pw", []string{"require_digit"}},
+		{"missing symbol", strict, "alice", "Secr3tpww", []string{"require_symbol"}},
+		{"contains username ignoring case", strict, "alice", "xALICE1!x", []string{"no_username"}},
+		{"username allowed when not disallowed", PasswordPolicy{MinLength: 1}, "alice", "alice", []string{}},
+		{"every rule broken", strict, "abc", "abc", []string{"min_length", "require_upper", "require_digit", "require_symbol", "no_username"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := tt.policy.Check(tt.username, tt.password)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if got := rules(details); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Check(%q, %q) broke %v, want %v", tt.username, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyCheckBreached(t *testing.T) {
+	policy := PasswordPolicy{Breached: &BreachedList{Path: writeBreachedList(t, "password1")}}
+
+	details, err := policy.Check("bob", "password1")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got := rules(details); len(got) != 1 || got[0] != "breached" {
+		t.Errorf("breached password broke %v, want [breached]", got)
+	}
+
+	details, err = policy.Check("bob", "password2")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(details) != 0 {
+		t.Errorf("unlisted password broke %v", rules(details))
+	}
+}
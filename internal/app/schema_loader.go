@@ -7,8 +7,15 @@ import (
 
 func loadSchemas() map[string]string {
 	files := map[string]string{
-		"user":    "schemas/user.json",
-		"project": "schemas/project.json",
+		"user":          "schemas/user.json",
+		"password":      "schemas/password.json",
+		"project":       "schemas/project.json",
+		"project_patch": "schemas/project_patch.json",
+		"tag":           "schemas/tag.json",
+		"tag_merge":     "schemas/tag_merge.json",
+		"template":      "schemas/template.json",
+		"transfer":      "schemas/transfer.json",
+		"view":          "schemas/view.json",
 	}
 
 	schemas := make(map[string]string)
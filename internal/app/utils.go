@@ -6,12 +6,22 @@ import (
 )
 
 type ErrorResponse struct {
+	Message string        `json:"message"`
+	Code    int           `json:"code"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+type ErrorDetail struct {
+	Rule    string `json:"rule"`
 	Message string `json:"message"`
-	Code    int    `json:"code"`
 }
 
 func RespondWithError(w http.ResponseWriter, code int, message string) {
+	RespondWithErrorDetails(w, code, message, nil)
+}
+
+func RespondWithErrorDetails(w http.ResponseWriter, code int, message string, details []ErrorDetail) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	_ = json.NewEncoder(w).Encode(ErrorResponse{Message: message, Code: code})
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Message: message, Code: code, Details: details})
 }
@@ -0,0 +1,306 @@
+package attachment
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/storage"
+)
+
+// Upload godoc
+// @Summary Upload a project attachment
+// @Description Attach a file to a project. The file is streamed from the "file" field of a multipart body and stored once per distinct content.
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} Attachment
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 413 {object} app.ErrorResponse
+// @Failure 415 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/attachments [post]
+func Upload(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		owner, archived, err := projectOwner(a.DB, projectID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
+			return
+		}
+		if archived {
+			app.RespondWithError(w, http.StatusConflict, "Project is archived")
+			return
+		}
+
+		// Leave room for the multipart framing around the file itself.
+		r.Body = http.MaxBytesReader(w, r.Body, a.AttachmentMaxBytes+1<<20)
+		mr, err := r.MultipartReader()
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Expected a multipart/form-data body")
+			return
+		}
+
+		var part io.Reader
+		var filename, contentType string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				app.RespondWithError(w, http.StatusBadRequest, "Missing file field")
+				return
+			}
+			if p.FormName() == "file" {
+				part = p
+				filename = filepath.Base(p.FileName())
+				contentType = p.Header.Get("Content-Type")
+				break
+			}
+		}
+		if filename == "" || filename == "." || filename == string(filepath.Separator) {
+			app.RespondWithError(w, http.StatusBadRequest, "Missing file name")
+			return
+		}
+		if utf8.RuneCountInString(filename) > maxColumnLength {
+			app.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("File name must be at most %d characters", maxColumnLength))
+			return
+		}
+		if utf8.RuneCountInString(contentType) > maxColumnLength {
+			app.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Content type must be at most %d characters", maxColumnLength))
+			return
+		}
+
+		tmp, err := os.CreateTemp("", "attachment-*")
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Upload failed")
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(part, a.AttachmentMaxBytes+1))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				app.RespondWithError(w, http.StatusRequestEntityTooLarge, "File too large")
+			} else {
+				app.RespondWithError(w, http.StatusBadRequest, "Upload interrupted")
+			}
+			return
+		}
+		if size > a.AttachmentMaxBytes {
+			app.RespondWithError(w, http.StatusRequestEntityTooLarge, "File too large")
+			return
+		}
+
+		if contentType == "" || contentType == "application/octet-stream" {
+			head := make([]byte, 512)
+			n, _ := tmp.ReadAt(head, 0)
+			contentType = http.DetectContentType(head[:n])
+		}
+		if !typeAllowed(contentType, a.AttachmentTypes) {
+			app.RespondWithError(w, http.StatusUnsupportedMediaType, "File type not allowed")
+			return
+		}
+
+		at := Attachment{
+			ProjectID:   projectID,
+			UserID:      claims.ID,
+			Filename:    filename,
+			ContentType: contentType,
+			Size:        size,
+			SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		}
+		if err := create(r.Context(), a.DB, a.Storage, &at, tmp); err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to store attachment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(at)
+	}
+}
+
+// GetAll godoc
+// @Summary List project attachments
+// @Description List the files attached to a project owned by the authenticated user
+// @Tags attachments
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {array} Attachment
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/attachments [get]
+func GetAll(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		owner, _, err := projectOwner(a.DB, projectID)
+		if err != nil || owner != claims.ID {
+			if err != nil && err != sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			} else {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			}
+			return
+		}
+
+		rows, err := a.DB.Query(`SELECT `+attachmentColumns+` FROM attachments
+			WHERE project_id=$1 ORDER BY created_at DESC, id DESC`, projectID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch attachments")
+			return
+		}
+		defer rows.Close()
+
+		attachments := []Attachment{}
+		for rows.Next() {
+			var at Attachment
+			if err := scanAttachment(rows, &at); err != nil {
+				app.RespondWithError(w, http.StatusInternalServerError, "Scan error")
+				return
+			}
+			attachments = append(attachments, at)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(attachments)
+	}
+}
+
+// Download godoc
+// @Summary Download a project attachment
+// @Description Stream the content of a file attached to a project owned by the authenticated user
+// @Tags attachments
+// @Produce octet-stream
+// @Param id path string true "Project ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {file} file
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/attachments/{attachmentId} [get]
+func Download(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var at Attachment
+		err := scanAttachment(a.DB.QueryRow(`SELECT `+attachmentColumns+` FROM attachments
+			WHERE id=$1 AND project_id=$2 AND EXISTS (SELECT 1 FROM projects p
+				WHERE p.id = attachments.project_id AND p.user_id=$3 AND p.deleted_at IS NULL)`,
+			vars["attachmentId"], vars["id"], claims.ID), &at)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Attachment not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+
+		etag := `"` + at.SHA256 + `"`
+		if app.NotModified(w, r, etag) {
+			return
+		}
+
+		content, err := a.Storage.Get(r.Context(), blobKey(at.SHA256))
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				app.RespondWithError(w, http.StatusNotFound, "Attachment content missing")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Failed to read attachment")
+			}
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", at.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(at.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": at.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("ETag", etag)
+		io.Copy(w, content)
+	}
+}
+
+// Delete godoc
+// @Summary Delete a project attachment
+// @Description Remove a file from a project owned by the authenticated user
+// @Tags attachments
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 204
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/attachments/{attachmentId} [delete]
+func Delete(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		owner, archived, err := projectOwner(a.DB, vars["id"])
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized to delete")
+			return
+		}
+		if archived {
+			app.RespondWithError(w, http.StatusConflict, "Project is archived")
+			return
+		}
+
+		if err := remove(r.Context(), a.DB, a.Storage, r, vars["id"], vars["attachmentId"]); err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Attachment not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,14 @@
+package attachment
+
+import "time"
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project"`
+	UserID      string    `json:"user"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
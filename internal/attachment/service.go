@@ -0,0 +1,225 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/nihsioK/go-kanban/internal/audit"
+	"github.com/nihsioK/go-kanban/internal/storage"
+)
+
+// maxColumnLength is the size of the filename and content_type columns.
+const maxColumnLength = 255
+
+const attachmentColumns = `id, project_id, user_id, filename, content_type, size, sha256, created_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAttachment(s scanner, at *Attachment) error {
+	return s.Scan(&at.ID, &at.ProjectID, &at.UserID, &at.Filename, &at.ContentType, &at.Size, &at.SHA256, &at.CreatedAt)
+}
+
+// blobKey is where content with the given SHA-256 lives in the store. Blobs
+// are shared by every attachment with the same content.
+func blobKey(sum string) string {
+	return "sha256/" + sum[:2] + "/" + sum
+}
+
+// projectOwner returns the owner of a live project and whether it is
+// archived.
+func projectOwner(db *sql.DB, projectID string) (string, bool, error) {
+	var owner string
+	var archived bool
+	err := db.QueryRow(`SELECT user_id, archived_at IS NOT NULL FROM projects
+		WHERE id=$1 AND deleted_at IS NULL`, projectID).Scan(&owner, &archived)
+	return owner, archived, err
+}
+
+// blobLockKey turns a checksum into the key of the advisory lock that
+// serializes uploading a blob with deleting it. Deleting holds the lock past
+// its commit, until the stored object is gone too.
+const blobLockKey = `hashtextextended($1, 0)`
+
+// create records an attachment, uploading its content only if no blob with
+// the same checksum is stored yet. The blob stays locked until the
+// attachment is committed so a concurrent remove cannot delete it in
+// between.
+func create(ctx context.Context, db *sql.DB, store storage.Store, at *Attachment, content io.ReadSeeker) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(`+blobLockKey+`)`, at.SHA256); err != nil {
+		return err
+	}
+
+	// Content uploaded for a blob row that never gets committed would be out
+	// of reach of CollectGarbage, so it is removed again right away.
+	var uploaded, committed bool
+	defer func() {
+		if uploaded && !committed {
+			if err := store.Delete(context.Background(), blobKey(at.SHA256)); err != nil {
+				log.Printf("Deleting uncommitted blob %s from storage failed: %v", at.SHA256, err)
+			}
+		}
+	}()
+
+	var inserted bool
+	err = tx.QueryRowContext(ctx, `INSERT INTO blobs (sha256, size) VALUES ($1, $2)
+		ON CONFLICT (sha256) DO UPDATE SET sha256=EXCLUDED.sha256
+		RETURNING xmax = 0`, at.SHA256, at.Size).Scan(&inserted)
+	if err != nil {
+		return err
+	}
+
+	if inserted {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		uploaded = true
+		if err := store.Put(ctx, blobKey(at.SHA256), content, at.Size, at.ContentType); err != nil {
+			return err
+		}
+	}
+
+	err = scanAttachment(tx.QueryRowContext(ctx, `INSERT INTO attachments
+		(project_id, user_id, filename, content_type, size, sha256)
+		VALUES ($1,$2,$3,$4,$5,$6) RETURNING `+attachmentColumns,
+		at.ProjectID, at.UserID, at.Filename, at.ContentType, at.Size, at.SHA256), at)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// remove deletes an attachment, together with its audit entry, and, when it
+// was the last one using its content, the blob as well. The attachment is
+// gone once its row is, so a blob that cannot be deleted is only logged.
+func remove(ctx context.Context, db *sql.DB, store storage.Store, r *http.Request, projectID, id string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sum string
+	err = tx.QueryRowContext(ctx, `DELETE FROM attachments WHERE id=$1 AND project_id=$2
+		RETURNING sha256`, id, projectID).Scan(&sum)
+	if err != nil {
+		return err
+	}
+	err = audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionAttachmentDeleted, TargetType: "attachment", TargetID: id},
+		map[string]interface{}{"project": projectID})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if _, err := deleteUnusedBlob(ctx, db, store, sum); err != nil {
+		log.Printf("Deleting blob %s failed: %v", sum, err)
+	}
+	return nil
+}
+
+// deleteUnusedBlob deletes a blob no attachment refers to any more. The
+// stored object is only deleted once the row is gone for good, so a failure
+// there leaves an orphaned object rather than a row without content, and is
+// only logged. It reports whether the blob was deleted.
+func deleteUnusedBlob(ctx context.Context, db *sql.DB, store storage.Store, sum string) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(`+blobLockKey+`)`, sum); err != nil {
+		return false, err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(`+blobLockKey+`)`, sum)
+
+	res, err := conn.ExecContext(ctx, `DELETE FROM blobs WHERE sha256=$1
+		AND NOT EXISTS (SELECT 1 FROM attachments WHERE sha256=$1)`, sum)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if err := store.Delete(ctx, blobKey(sum)); err != nil {
+		log.Printf("Deleting blob %s from storage failed: %v", sum, err)
+	}
+	return true, nil
+}
+
+// CollectGarbage deletes every blob no attachment refers to any more, such
+// as those left behind when purging projects cascades to their attachments,
+// and returns how many it deleted.
+func CollectGarbage(ctx context.Context, db *sql.DB, store storage.Store) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT sha256 FROM blobs b
+		WHERE NOT EXISTS (SELECT 1 FROM attachments a WHERE a.sha256 = b.sha256)`)
+	if err != nil {
+		return 0, err
+	}
+	var sums []string
+	for rows.Next() {
+		var sum string
+		if err := rows.Scan(&sum); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sums = append(sums, sum)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, sum := range sums {
+		ok, err := deleteUnusedBlob(ctx, db, store, sum)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// typeAllowed matches a media type against a list of exact types and
+// "type/*" wildcards. An empty list allows everything.
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
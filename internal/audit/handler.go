@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// GetAll godoc
+// @Summary Query the audit log
+// @Description Retrieve a page of audit log entries, newest first. Only users listed in ADMIN_USERS may call this.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param actor query string false "Only entries by this user ID"
+// @Param action query string false "Only entries with this action, e.g. auth.login_failed"
+// @Param target_type query string false "Only entries about this kind of object"
+// @Param target_id query string false "Only entries about the object with this ID"
+// @Param since query string false "Only entries at or after this RFC 3339 time"
+// @Param until query string false "Only entries before this RFC 3339 time"
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Success 200 {object} EntryPage
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func GetAll(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := QueryOptions{
+			ActorID:    q.Get("actor"),
+			Action:     q.Get("action"),
+			TargetType: q.Get("target_type"),
+			TargetID:   q.Get("target_id"),
+		}
+
+		var err error
+		for param, dst := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+			if v := q.Get(param); v != "" {
+				if *dst, err = time.Parse(time.RFC3339, v); err != nil {
+					app.RespondWithError(w, http.StatusBadRequest, param+" must be an RFC 3339 time")
+					return
+				}
+			}
+		}
+
+		if opts.Limit, err = app.ParseLimit(q); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Cursor, err = app.DecodeCursor(q.Get("cursor"))
+		if err != nil || (opts.Cursor != nil && opts.Cursor.Sort != "seq") {
+			app.RespondWithError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
+		entries, next, err := Query(a.DB, opts)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch audit log")
+			return
+		}
+
+		app.SetNextLink(w, r, next)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(EntryPage{Data: entries, NextCursor: next})
+	}
+}
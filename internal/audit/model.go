@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ActionLogin             = "auth.login"
+	ActionLoginFailed       = "auth.login_failed"
+	ActionTokenCreated      = "auth.token_created"
+	ActionPasswordChanged   = "auth.password_changed"
+	ActionOwnerChanged      = "project.owner_changed"
+	ActionProjectDeleted    = "project.deleted"
+	ActionProjectPurged     = "project.purged"
+	ActionTemplateDeleted   = "template.deleted"
+	ActionTagDeleted        = "tag.deleted"
+	ActionTagMerged         = "tag.merged"
+	ActionViewDeleted       = "view.deleted"
+	ActionAttachmentDeleted = "attachment.deleted"
+)
+
+// Entry is one record of the audit log. Hash covers every other field and
+// the hash of the entry before it, so editing, removing or reordering
+// entries breaks the chain from that point on.
+type Entry struct {
+	Seq           int64           `json:"seq"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ActorID       string          `json:"actor,omitempty"`
+	ActorUsername string          `json:"actor_username,omitempty"`
+	Action        string          `json:"action"`
+	TargetType    string          `json:"target_type,omitempty"`
+	TargetID      string          `json:"target_id,omitempty"`
+	IP            string          `json:"ip,omitempty"`
+	Details       json.RawMessage `json:"details" swaggertype:"object"`
+	PrevHash      string          `json:"prev_hash"`
+	Hash          string          `json:"hash"`
+}
+
+type EntryPage struct {
+	Data       []Entry `json:"data"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// Problem is an inconsistency found while verifying the chain.
+type Problem struct {
+	Seq     int64  `json:"seq"`
+	Message string `json:"message"`
+}
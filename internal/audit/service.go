@@ -0,0 +1,256 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// GenesisHash is the previous hash of the first entry.
+var GenesisHash = strings.Repeat("0", 64)
+
+const entryColumns = `seq, created_at, actor_id, actor_username, action, target_type, target_id, ip, details, prev_hash, hash`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(s scanner, e *Entry) error {
+	var details []byte
+	if err := s.Scan(&e.Seq, &e.CreatedAt, &e.ActorID, &e.ActorUsername, &e.Action,
+		&e.TargetType, &e.TargetID, &e.IP, &details, &e.PrevHash, &e.Hash); err != nil {
+		return err
+	}
+	e.Details = json.RawMessage(details)
+	return nil
+}
+
+// ComputeHash returns the hash the entry should carry. Details are hashed
+// exactly as stored, which is why the column is json rather than jsonb.
+func (e Entry) ComputeHash() string {
+	content, _ := json.Marshal(struct {
+		Seq           int64           `json:"seq"`
+		CreatedAt     string          `json:"created_at"`
+		ActorID       string          `json:"actor"`
+		ActorUsername string          `json:"actor_username"`
+		Action        string          `json:"action"`
+		TargetType    string          `json:"target_type"`
+		TargetID      string          `json:"target_id"`
+		IP            string          `json:"ip"`
+		Details       json.RawMessage `json:"details"`
+	}{e.Seq, e.CreatedAt.UTC().Format(time.RFC3339Nano), e.ActorID, e.ActorUsername, e.Action,
+		e.TargetType, e.TargetID, e.IP, e.Details})
+
+	sum := sha256.Sum256(append([]byte(e.PrevHash), content...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Append links e to the end of the chain and stores it in a transaction of
+// its own.
+func Append(db *sql.DB, e Entry) (Entry, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	if e, err = appendTx(tx, e); err != nil {
+		return e, err
+	}
+	return e, tx.Commit()
+}
+
+// appendTx links e to the end of the chain within tx. Appenders take an
+// advisory lock on the chain head until tx ends so two entries can never
+// claim the same predecessor; nothing else touching the table is blocked.
+// Callers should append last, right before committing, to hold it briefly.
+func appendTx(tx *sql.Tx, e Entry) (Entry, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('audit_log'))`); err != nil {
+		return e, err
+	}
+
+	e.Seq, e.PrevHash = 1, GenesisHash
+	var last int64
+	var lastHash string
+	err := tx.QueryRow(`SELECT seq, hash FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan(&last, &lastHash)
+	if err == nil {
+		e.Seq, e.PrevHash = last+1, lastHash
+	} else if err != sql.ErrNoRows {
+		return e, err
+	}
+
+	// Postgres keeps microseconds; anything finer would not survive the
+	// round trip and the hash would no longer match.
+	e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if len(e.Details) == 0 {
+		e.Details = json.RawMessage("{}")
+	}
+	e.Hash = e.ComputeHash()
+
+	_, err = tx.Exec(`INSERT INTO audit_log (`+entryColumns+`)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
+		e.Seq, e.CreatedAt, e.ActorID, e.ActorUsername, e.Action, e.TargetType, e.TargetID,
+		e.IP, []byte(e.Details), e.PrevHash, e.Hash)
+	return e, err
+}
+
+// newEntry fills in who did something and from where from r, which may be
+// nil for background work, and encodes the details. The actor defaults to
+// the authenticated user.
+func newEntry(r *http.Request, e Entry, details map[string]interface{}) (Entry, error) {
+	if r != nil {
+		if claims, ok := r.Context().Value("claims").(*app.Claims); ok && e.ActorID == "" {
+			e.ActorID, e.ActorUsername = claims.ID, claims.Username
+		}
+		e.IP = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			e.IP = host
+		}
+	}
+
+	var err error
+	if details != nil {
+		e.Details, err = json.Marshal(details)
+	}
+	return e, err
+}
+
+// Record appends an entry for something done while serving r, which may be
+// nil for background work. The action has already happened, so a failure
+// is logged rather than reported to the client. Actions that run in a
+// transaction should use RecordTx instead.
+func Record(db *sql.DB, r *http.Request, e Entry, details map[string]interface{}) {
+	e, err := newEntry(r, e, details)
+	if err == nil {
+		_, err = Append(db, e)
+	}
+	if err != nil {
+		log.Printf("Audit log append for %s failed: %v", e.Action, err)
+	}
+}
+
+// RecordTx appends an entry for an action made in tx, so the entry is
+// committed together with the action or not at all. The caller must give
+// up on the action if it fails.
+func RecordTx(tx *sql.Tx, r *http.Request, e Entry, details map[string]interface{}) error {
+	e, err := newEntry(r, e, details)
+	if err != nil {
+		return err
+	}
+	_, err = appendTx(tx, e)
+	return err
+}
+
+// Verify walks the whole chain in order and reports every gap in the
+// sequence, broken link and entry whose content no longer matches its
+// hash. It returns the last entry so the head can be compared with a copy
+// kept elsewhere, which is the only way to notice entries cut off the end.
+func Verify(db *sql.DB) ([]Problem, *Entry, error) {
+	rows, err := db.Query(`SELECT ` + entryColumns + ` FROM audit_log ORDER BY seq`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	problems := []Problem{}
+	var prev *Entry
+	for rows.Next() {
+		var e Entry
+		if err := scanEntry(rows, &e); err != nil {
+			return nil, nil, err
+		}
+
+		expectSeq, expectPrev := int64(1), GenesisHash
+		if prev != nil {
+			expectSeq, expectPrev = prev.Seq+1, prev.Hash
+		}
+		if e.Seq != expectSeq {
+			problems = append(problems, Problem{e.Seq, fmt.Sprintf("entries %d to %d are missing", expectSeq, e.Seq-1)})
+		} else if e.PrevHash != expectPrev {
+			problems = append(problems, Problem{e.Seq, "previous hash does not match the entry before it"})
+		}
+		if e.ComputeHash() != e.Hash {
+			problems = append(problems, Problem{e.Seq, "content does not match its hash"})
+		}
+		prev = &e
+	}
+	return problems, prev, rows.Err()
+}
+
+type QueryOptions struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Cursor     *app.Cursor
+}
+
+// Query returns a page of entries matching opts, newest first.
+func Query(db *sql.DB, opts QueryOptions) ([]Entry, string, error) {
+	where := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if opts.ActorID != "" {
+		where = append(where, "actor_id="+arg(opts.ActorID))
+	}
+	if opts.Action != "" {
+		where = append(where, "action="+arg(opts.Action))
+	}
+	if opts.TargetType != "" {
+		where = append(where, "target_type="+arg(opts.TargetType))
+	}
+	if opts.TargetID != "" {
+		where = append(where, "target_id="+arg(opts.TargetID))
+	}
+	if !opts.Since.IsZero() {
+		where = append(where, "created_at >= "+arg(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		where = append(where, "created_at < "+arg(opts.Until))
+	}
+	if opts.Cursor != nil {
+		where = append(where, "seq < "+arg(opts.Cursor.ID)+"::bigint")
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT %s FROM audit_log WHERE %s ORDER BY seq DESC LIMIT %s`,
+		entryColumns, strings.Join(where, " AND "), arg(opts.Limit+1)), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := scanEntry(rows, &e); err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+		next = app.Cursor{Sort: "seq", ID: fmt.Sprint(entries[len(entries)-1].Seq)}.Encode()
+	}
+	return entries, next, nil
+}
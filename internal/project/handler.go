@@ -3,47 +3,76 @@ package project
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
+	"github.com/nihsioK/go-kanban/internal/activity"
 	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/audit"
 )
 
 // Create godoc
 // @Summary Create a new project
-// @Description Create a new project for the authenticated user
+// @Description Create a new project for the authenticated user, optionally starting from a template. Fields given in the body override the template's.
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param project body Project true "Project details"
+// @Param template query string false "ID of the template to start from"
 // @Success 201 {object} Project
 // @Failure 400 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
 // @Failure 500 {object} app.ErrorResponse
 // @Security BearerAuth
 // @Router /projects [post]
 func Create(a *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var project Project
-		if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
-			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
 			return
 		}
 
 		claims := r.Context().Value("claims").(*app.Claims)
-		project.UserID = claims.ID
 
-		err := a.DB.QueryRow(`INSERT INTO projects 
-			(user_id, name, repo_url, site_url, description, dependencies, dev_dependencies, status)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`,
-			project.UserID, project.Name, project.RepoURL, project.SiteURL,
-			project.Description, pq.Array(project.Dependencies), pq.Array(project.DevDependencies), "active",
-		).Scan(&project.ID)
+		var template *Template
+		if templateID := r.URL.Query().Get("template"); templateID != "" {
+			t, err := visibleTemplate(a.DB, templateID, claims.ID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					app.RespondWithError(w, http.StatusNotFound, "Template not found")
+				} else {
+					app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+				}
+				return
+			}
+			template = &t
+		}
 
+		fields, errs, err := newProjectFields(a, template, body)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Schema validation error")
+			return
+		}
+		if len(errs) > 0 {
+			app.RespondWithError(w, http.StatusBadRequest, strings.Join(errs, ", "))
+			return
+		}
+
+		project, err := insertProject(a.DB, claims.ID, fields)
 		if err != nil {
 			app.RespondWithError(w, http.StatusInternalServerError, "Failed to create project")
 			return
 		}
+		recordActivity(a, claims.ID, activity.ActionCreated, project.ID, diffFields(Fields{}, project.Fields()))
+		w.Header().Set("ETag", app.ETag(project.Version))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(project)
 	}
@@ -57,10 +86,14 @@ func Create(a *app.App) http.HandlerFunc {
 // @Produce json
 // @Param id path string true "Project ID"
 // @Param project body Project true "Updated project details"
+// @Param If-Match header string false "ETag the project must still have"
 // @Success 200 {object} Project
 // @Failure 400 {object} app.ErrorResponse
 // @Failure 404 {object} app.ErrorResponse
 // @Failure 403 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 412 {object} app.ErrorResponse
+// @Failure 428 {object} app.ErrorResponse
 // @Failure 500 {object} app.ErrorResponse
 // @Security BearerAuth
 // @Router /projects/{id} [put]
@@ -77,8 +110,8 @@ func Update(a *app.App) http.HandlerFunc {
 
 		claims := r.Context().Value("claims").(*app.Claims)
 
-		var storedUserID string
-		if err := a.DB.QueryRow("SELECT user_id FROM projects WHERE id=$1", id).Scan(&storedUserID); err != nil {
+		var current Project
+		if err := scanProject(a.DB.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id=$1 AND deleted_at IS NULL`, id), &current); err != nil {
 			if err == sql.ErrNoRows {
 				app.RespondWithError(w, http.StatusNotFound, "Project not found")
 			} else {
@@ -86,63 +119,185 @@ func Update(a *app.App) http.HandlerFunc {
 			}
 			return
 		}
-		if storedUserID != claims.ID {
+		if current.UserID != claims.ID {
 			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
 			return
 		}
+		if current.ArchivedAt != nil {
+			app.RespondWithError(w, http.StatusConflict, "Project is archived")
+			return
+		}
+		if !a.CheckIfMatch(w, r, app.ETag(current.Version)) {
+			return
+		}
 
-		_, err := a.DB.Exec(`UPDATE projects 
+		err := a.DB.QueryRow(`UPDATE projects 
 			SET name=$1, repo_url=$2, site_url=$3, description=$4, dependencies=$5, dev_dependencies=$6, status=$7
-			WHERE id=$8 AND user_id=$9`,
+			WHERE id=$8 AND user_id=$9 AND deleted_at IS NULL AND archived_at IS NULL AND ($10 = 0 OR version=$10)
+			RETURNING version, created_at, updated_at`,
 			project.Name, project.RepoURL, project.SiteURL, project.Description,
 			pq.Array(project.Dependencies), pq.Array(project.DevDependencies), project.Status, id, claims.ID,
-		)
+			app.ConditionalVersion(r, current.Version),
+		).Scan(&project.Version, &project.CreatedAt, &project.UpdatedAt)
 
 		if err != nil {
-			app.RespondWithError(w, http.StatusInternalServerError, "Update failed")
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusPreconditionFailed, "Resource has been modified")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Update failed")
+			}
 			return
 		}
 
 		project.ID = id
 		project.UserID = claims.ID
+		if changes := diffFields(current.Fields(), project.Fields()); len(changes) > 0 {
+			recordActivity(a, claims.ID, activity.ActionUpdated, id, changes)
+		}
+		w.Header().Set("ETag", app.ETag(project.Version))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(project)
 	}
 }
 
+// Patch godoc
+// @Summary Partially update a project
+// @Description Apply a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json) to a project. Only the fields the patch changes are written.
+// @Tags projects
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param patch body Fields true "Merge patch of project fields, or an array of JSON Patch operations"
+// @Param If-Match header string false "ETag the project must still have"
+// @Success 200 {object} Project
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 412 {object} app.ErrorResponse
+// @Failure 415 {object} app.ErrorResponse
+// @Failure 422 {object} app.ErrorResponse
+// @Failure 428 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id} [patch]
+func Patch(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			app.RespondWithError(w, http.StatusUnsupportedMediaType, "Missing or invalid Content-Type")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		var p Project
+		if err := scanProject(a.DB.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id=$1 AND deleted_at IS NULL`, id), &p); err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+		if p.UserID != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
+			return
+		}
+		if p.ArchivedAt != nil {
+			app.RespondWithError(w, http.StatusConflict, "Project is archived")
+			return
+		}
+		if !a.CheckIfMatch(w, r, app.ETag(p.Version)) {
+			return
+		}
+
+		fields, err := applyPatch(a, p.Fields(), mediaType, body)
+		if err != nil {
+			var perr *patchError
+			if errors.As(err, &perr) {
+				app.RespondWithError(w, perr.Code, perr.Message)
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Patch failed")
+			}
+			return
+		}
+
+		old := p.Fields()
+		cols, vals := changedColumns(old, fields)
+		if len(cols) > 0 {
+			sets := make([]string, len(cols))
+			for i, col := range cols {
+				sets[i] = fmt.Sprintf("%s=$%d", col, i+1)
+			}
+			vals = append(vals, id, claims.ID, app.ConditionalVersion(r, p.Version))
+			err = scanProject(a.DB.QueryRow(fmt.Sprintf(`UPDATE projects SET %s
+				WHERE id=$%d AND user_id=$%d AND deleted_at IS NULL AND archived_at IS NULL AND ($%d = 0 OR version=$%d)
+				RETURNING `+projectColumns,
+				strings.Join(sets, ", "), len(vals)-2, len(vals)-1, len(vals), len(vals)), vals...), &p)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					app.RespondWithError(w, http.StatusPreconditionFailed, "Resource has been modified")
+				} else {
+					app.RespondWithError(w, http.StatusInternalServerError, "Update failed")
+				}
+				return
+			}
+			recordActivity(a, claims.ID, activity.ActionUpdated, id, diffFields(old, p.Fields()))
+		}
+
+		w.Header().Set("ETag", app.ETag(p.Version))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
 // GetAll godoc
-// @Summary Get all projects for the authenticated user
-// @Description Retrieve all projects for the authenticated user
+// @Summary List projects for the authenticated user
+// @Description Retrieve a page of the authenticated user's projects, newest first by default. Follow next_cursor (or the Link header) for the next page.
 // @Tags projects
 // @Accept json
 // @Produce json
-// @Success 200 {array} Project
+// @Param status query string false "Comma-separated statuses to include"
+// @Param dependency query string false "Only projects depending on this package"
+// @Param tag query string false "Comma-separated tag names; projects carrying any of them match"
+// @Param include_archived query bool false "Include archived projects"
+// @Param q query string false "Text to match in name or description"
+// @Param updated_within query string false "Only projects updated within this period, e.g. 7d or 36h"
+// @Param sort query string false "created_at, updated_at or name, prefixed with - for descending" default(-created_at)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Success 200 {object} ProjectPage
+// @Failure 400 {object} app.ErrorResponse
 // @Failure 500 {object} app.ErrorResponse
 // @Security BearerAuth
 // @Router /projects [get]
 func GetAll(a *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims := r.Context().Value("claims").(*app.Claims)
-		rows, err := a.DB.Query(`SELECT id, user_id, name, repo_url, site_url, description, dependencies, dev_dependencies, status 
-			FROM projects WHERE user_id=$1`, claims.ID)
+
+		opts, err := ParseListOptions(r.URL.Query())
 		if err != nil {
-			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch projects")
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		defer rows.Close()
 
-		var projects []Project
-		for rows.Next() {
-			var p Project
-			if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.RepoURL, &p.SiteURL, &p.Description,
-				pq.Array(&p.Dependencies), pq.Array(&p.DevDependencies), &p.Status); err != nil {
-				app.RespondWithError(w, http.StatusInternalServerError, "Scan error")
-				return
-			}
-			projects = append(projects, p)
+		projects, next, err := ListProjects(a.DB, claims.ID, opts)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch projects")
+			return
 		}
+
+		app.SetNextLink(w, r, next)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(projects)
+		json.NewEncoder(w).Encode(ProjectPage{Data: projects, NextCursor: next})
 	}
 }
 
@@ -153,7 +308,9 @@ func GetAll(a *app.App) http.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param id path string true "Project ID"
+// @Param If-None-Match header string false "ETag of the cached copy"
 // @Success 200 {object} Project
+// @Success 304
 // @Failure 404 {object} app.ErrorResponse
 // @Failure 403 {object} app.ErrorResponse
 // @Failure 500 {object} app.ErrorResponse
@@ -165,10 +322,8 @@ func GetOne(a *app.App) http.HandlerFunc {
 		claims := r.Context().Value("claims").(*app.Claims)
 
 		var p Project
-		err := a.DB.QueryRow(`SELECT id, user_id, name, repo_url, site_url, description, dependencies, dev_dependencies, status 
-			FROM projects WHERE id=$1 AND user_id=$2`, id, claims.ID).
-			Scan(&p.ID, &p.UserID, &p.Name, &p.RepoURL, &p.SiteURL, &p.Description,
-				pq.Array(&p.Dependencies), pq.Array(&p.DevDependencies), &p.Status)
+		err := scanProject(a.DB.QueryRow(`SELECT `+projectColumns+`
+			FROM projects WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL`, id, claims.ID), &p)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -178,6 +333,12 @@ func GetOne(a *app.App) http.HandlerFunc {
 			}
 			return
 		}
+
+		etag := app.ETag(p.Version)
+		if app.NotModified(w, r, etag) {
+			return
+		}
+		w.Header().Set("ETag", etag)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(p)
 	}
@@ -185,14 +346,18 @@ func GetOne(a *app.App) http.HandlerFunc {
 
 // Delete godoc
 // @Summary Delete a project
-// @Description Delete a project by ID for the authenticated user
+// @Description Move a project to the authenticated user's trash. It can be restored until the trash retention period expires.
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param id path string true "Project ID"
+// @Param If-Match header string false "ETag the project must still have"
 // @Success 204
 // @Failure 404 {object} app.ErrorResponse
 // @Failure 403 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 412 {object} app.ErrorResponse
+// @Failure 428 {object} app.ErrorResponse
 // @Failure 500 {object} app.ErrorResponse
 // @Security BearerAuth
 // @Router /projects/{id} [delete]
@@ -202,7 +367,10 @@ func Delete(a *app.App) http.HandlerFunc {
 		claims := r.Context().Value("claims").(*app.Claims)
 
 		var owner string
-		err := a.DB.QueryRow("SELECT user_id FROM projects WHERE id=$1", id).Scan(&owner)
+		var version int
+		var archived bool
+		err := a.DB.QueryRow("SELECT user_id, version, archived_at IS NOT NULL FROM projects WHERE id=$1 AND deleted_at IS NULL", id).
+			Scan(&owner, &version, &archived)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				app.RespondWithError(w, http.StatusNotFound, "Project not found")
@@ -215,13 +383,236 @@ func Delete(a *app.App) http.HandlerFunc {
 			app.RespondWithError(w, http.StatusForbidden, "Not authorized to delete")
 			return
 		}
+		if archived {
+			app.RespondWithError(w, http.StatusConflict, "Project is archived")
+			return
+		}
+		if !a.CheckIfMatch(w, r, app.ETag(version)) {
+			return
+		}
+
+		tx, err := a.DB.Begin()
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
+			return
+		}
+		defer tx.Rollback()
 
-		_, err = a.DB.Exec("DELETE FROM projects WHERE id=$1 AND user_id=$2", id, claims.ID)
+		res, err := tx.Exec(`UPDATE projects SET deleted_at=CURRENT_TIMESTAMP
+			WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL AND archived_at IS NULL AND ($3 = 0 OR version=$3)`,
+			id, claims.ID, app.ConditionalVersion(r, version))
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			app.RespondWithError(w, http.StatusPreconditionFailed, "Resource has been modified")
+			return
+		}
+		err = audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionProjectDeleted, TargetType: "project", TargetID: id}, nil)
+		if err == nil {
+			err = tx.Commit()
+		}
 		if err != nil {
 			app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
 			return
 		}
+		recordActivity(a, claims.ID, activity.ActionDeleted, id, nil)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// Trash godoc
+// @Summary List deleted projects
+// @Description Retrieve a page of the authenticated user's projects that are in the trash
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param sort query string false "created_at, updated_at or name, prefixed with - for descending" default(-created_at)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Success 200 {object} ProjectPage
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /trash [get]
+func Trash(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		opts, err := ParseListOptions(r.URL.Query())
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Trashed = true
+
+		projects, next, err := ListProjects(a.DB, claims.ID, opts)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch projects")
+			return
+		}
+
+		app.SetNextLink(w, r, next)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ProjectPage{Data: projects, NextCursor: next})
+	}
+}
+
+// Restore godoc
+// @Summary Restore a deleted project
+// @Description Move a project out of the authenticated user's trash
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} Project
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/restore [post]
+func Restore(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var p Project
+		err := scanProject(a.DB.QueryRow(`UPDATE projects SET deleted_at=NULL
+			WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL RETURNING `+projectColumns, id, claims.ID), &p)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found in trash")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Restore failed")
+			}
+			return
+		}
+		recordActivity(a, claims.ID, activity.ActionRestored, id, nil)
+
+		w.Header().Set("ETag", app.ETag(p.Version))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+// Archive godoc
+// @Summary Archive a project
+// @Description Make a project read-only and hide it from default listings. Archived projects stay searchable.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} Project
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/archive [post]
+func Archive(a *app.App) http.HandlerFunc {
+	return setArchived(a, true)
+}
+
+// Unarchive godoc
+// @Summary Unarchive a project
+// @Description Make an archived project writable again and show it in default listings
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} Project
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/unarchive [post]
+func Unarchive(a *app.App) http.HandlerFunc {
+	return setArchived(a, false)
+}
+
+func setArchived(a *app.App, archived bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		set, action := "archived_at=NULL", activity.ActionUnarchived
+		if archived {
+			set, action = "archived_at=coalesce(archived_at, CURRENT_TIMESTAMP)", activity.ActionArchived
+		}
+
+		var p Project
+		err := scanProject(a.DB.QueryRow(`UPDATE projects SET `+set+`
+			WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL RETURNING `+projectColumns, id, claims.ID), &p)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Update failed")
+			}
+			return
+		}
+		recordActivity(a, claims.ID, action, id, nil)
+
+		w.Header().Set("ETag", app.ETag(p.Version))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+// Clone godoc
+// @Summary Clone a project
+// @Description Create a new project for the authenticated user with the metadata and dependencies of an existing one
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param clone body CloneRequest false "Name of the copy"
+// @Success 201 {object} Project
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/clone [post]
+func Clone(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var req CloneRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		var source Project
+		err := scanProject(a.DB.QueryRow(`SELECT `+projectColumns+`
+			FROM projects WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL`, id, claims.ID), &source)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+
+		fields := source.Fields()
+		fields.Name = truncateName("Copy of " + source.Name)
+		if name := strings.TrimSpace(req.Name); name != "" {
+			if utf8.RuneCountInString(name) > maxNameLength {
+				app.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Name must be at most %d characters", maxNameLength))
+				return
+			}
+			fields.Name = name
+		}
+
+		project, err := insertProject(a.DB, claims.ID, fields)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to clone project")
+			return
+		}
+		recordActivity(a, claims.ID, activity.ActionCreated, project.ID, diffFields(Fields{}, project.Fields()))
+		w.Header().Set("ETag", app.ETag(project.Version))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(project)
+	}
+}
@@ -1,8 +1,28 @@
 package project
 
+import "time"
+
 type Project struct {
-	ID              string   `json:"id,omitempty"`
-	UserID          string   `json:"user,omitempty"`
+	ID              string     `json:"id,omitempty"`
+	UserID          string     `json:"user,omitempty"`
+	Name            string     `json:"name,omitempty"`
+	RepoURL         string     `json:"repo_url,omitempty"`
+	SiteURL         string     `json:"site_url,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	Dependencies    []string   `json:"dependencies,omitempty"`
+	DevDependencies []string   `json:"dev_dependencies,omitempty"`
+	Status          string     `json:"status,omitempty"`
+	Version         int        `json:"version,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ArchivedAt      *time.Time `json:"archived_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+}
+
+// Fields holds the user-editable columns of a project. It is the document
+// PATCH requests are applied to.
+type Fields struct {
 	Name            string   `json:"name,omitempty"`
 	RepoURL         string   `json:"repo_url,omitempty"`
 	SiteURL         string   `json:"site_url,omitempty"`
@@ -11,3 +31,31 @@ type Project struct {
 	DevDependencies []string `json:"dev_dependencies,omitempty"`
 	Status          string   `json:"status,omitempty"`
 }
+
+type ProjectPage struct {
+	Data       []Project `json:"data"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type CloneRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+// Template is a reusable set of project fields that new projects can be
+// created from.
+type Template struct {
+	ID          string    `json:"id,omitempty"`
+	UserID      string    `json:"user,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Fields      Fields    `json:"fields"`
+	Shared      bool      `json:"shared"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type TemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Shared      bool   `json:"shared,omitempty"`
+}
@@ -1 +1,572 @@
 package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/lib/pq"
+	"github.com/nihsioK/go-kanban/internal/activity"
+	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/attachment"
+	"github.com/nihsioK/go-kanban/internal/audit"
+)
+
+const projectColumns = `id, user_id, name, repo_url, site_url, description, dependencies, dev_dependencies, status, version,
+	created_at, updated_at, archived_at, deleted_at,
+	ARRAY(SELECT t.name FROM project_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.project_id = projects.id AND t.user_id = projects.user_id ORDER BY lower(t.name)) AS tags`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProject(s scanner, p *Project) error {
+	return s.Scan(&p.ID, &p.UserID, &p.Name, &p.RepoURL, &p.SiteURL, &p.Description,
+		pq.Array(&p.Dependencies), pq.Array(&p.DevDependencies), &p.Status, &p.Version, &p.CreatedAt, &p.UpdatedAt, &p.ArchivedAt, &p.DeletedAt, pq.Array(&p.Tags))
+}
+
+// sortColumns maps the values accepted by the "sort" query parameter to the
+// column the listing is ordered by and the Postgres type of its cursor value.
+var sortColumns = map[string]string{
+	"created_at": "timestamptz",
+	"updated_at": "timestamptz",
+	"name":       "text",
+}
+
+type ListOptions struct {
+	Status          []string
+	Dependency      string
+	Tags            []string
+	Query           string
+	UpdatedWithin   time.Duration
+	IncludeArchived bool
+	Sort            string
+	Desc            bool
+	Limit           int
+	Cursor          *app.Cursor
+	Trashed         bool
+}
+
+// ParseListOptions reads the listing filters, sort order and page position
+// from the query string of a GetAll request.
+func ParseListOptions(q url.Values) (ListOptions, error) {
+	opts := ListOptions{Sort: "created_at", Desc: true}
+
+	for _, v := range q["status"] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				opts.Status = append(opts.Status, s)
+			}
+		}
+	}
+	opts.Dependency = strings.TrimSpace(q.Get("dependency"))
+	for _, v := range q["tag"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				opts.Tags = append(opts.Tags, strings.ToLower(t))
+			}
+		}
+	}
+	opts.Query = strings.TrimSpace(q.Get("q"))
+
+	if v := q.Get("include_archived"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid include_archived %q", v)
+		}
+		opts.IncludeArchived = b
+	}
+
+	if v := q.Get("updated_within"); v != "" {
+		d, err := parseWithin(v)
+		if err != nil {
+			return opts, err
+		}
+		opts.UpdatedWithin = d
+	}
+
+	if sort := q.Get("sort"); sort != "" {
+		opts.Desc = strings.HasPrefix(sort, "-")
+		opts.Sort = strings.TrimPrefix(sort, "-")
+		if _, ok := sortColumns[opts.Sort]; !ok {
+			return opts, fmt.Errorf("cannot sort by %q", opts.Sort)
+		}
+	}
+
+	limit, err := app.ParseLimit(q)
+	if err != nil {
+		return opts, err
+	}
+	opts.Limit = limit
+
+	cursor, err := app.DecodeCursor(q.Get("cursor"))
+	if err != nil {
+		return opts, err
+	}
+	if cursor != nil && cursor.Sort != opts.sortParam() {
+		return opts, errors.New("cursor does not match sort order")
+	}
+	if cursor != nil && sortColumns[opts.Sort] == "timestamptz" {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return opts, errors.New("invalid cursor")
+		}
+	}
+	opts.Cursor = cursor
+
+	return opts, nil
+}
+
+func (o ListOptions) sortParam() string {
+	if o.Desc {
+		return "-" + o.Sort
+	}
+	return o.Sort
+}
+
+func (o ListOptions) cursorValue(p Project) string {
+	switch o.Sort {
+	case "updated_at":
+		return p.UpdatedAt.Format(time.RFC3339Nano)
+	case "name":
+		return p.Name
+	default:
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// ListProjects returns one page of the user's projects matching opts and the
+// cursor of the following page, which is empty on the last page.
+func ListProjects(db *sql.DB, userID string, opts ListOptions) ([]Project, string, error) {
+	where := []string{"user_id=$1", "deleted_at IS NULL"}
+	if opts.Trashed {
+		where[1] = "deleted_at IS NOT NULL"
+	} else if !opts.IncludeArchived {
+		where = append(where, "archived_at IS NULL")
+	}
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(opts.Status) > 0 {
+		where = append(where, "status = ANY("+arg(pq.Array(opts.Status))+")")
+	}
+	if opts.Dependency != "" {
+		p := arg(opts.Dependency)
+		where = append(where, fmt.Sprintf("(dependencies @> ARRAY[%s::text] OR dev_dependencies @> ARRAY[%s::text])", p, p))
+	}
+	if len(opts.Tags) > 0 {
+		where = append(where, `EXISTS (SELECT 1 FROM project_tags pt JOIN tags t ON t.id = pt.tag_id
+			WHERE pt.project_id = projects.id AND t.user_id = projects.user_id AND lower(t.name) = ANY(`+arg(pq.Array(opts.Tags))+`))`)
+	}
+	if opts.Query != "" {
+		p := arg("%" + escapeLike(opts.Query) + "%")
+		where = append(where, fmt.Sprintf("(name ILIKE %s OR description ILIKE %s)", p, p))
+	}
+
+	if opts.UpdatedWithin > 0 {
+		where = append(where, "updated_at >= "+arg(time.Now().Add(-opts.UpdatedWithin)))
+	}
+
+	dir, cmp := "ASC", ">"
+	if opts.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if opts.Cursor != nil {
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s::%s, %s::bigint)",
+			opts.Sort, cmp, arg(opts.Cursor.Value), sortColumns[opts.Sort], arg(opts.Cursor.ID)))
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM projects WHERE %s ORDER BY %s %s, id %s LIMIT %s`,
+		projectColumns, strings.Join(where, " AND "), opts.Sort, dir, dir, arg(opts.Limit+1))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	projects := []Project{}
+	for rows.Next() {
+		var p Project
+		if err := scanProject(rows, &p); err != nil {
+			return nil, "", err
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(projects) > opts.Limit {
+		projects = projects[:opts.Limit]
+		last := projects[len(projects)-1]
+		next = app.Cursor{Sort: opts.sortParam(), Value: opts.cursorValue(last), ID: last.ID}.Encode()
+	}
+	return projects, next, nil
+}
+
+// parseWithin accepts a Go duration ("36h") or a whole number of days
+// ("7d"), which is what saved views are usually built with.
+func parseWithin(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid updated_within %q", v)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// newProjectFields returns the fields of a project created from body, on
+// top of template's when one is given, or the schema violations that stop
+// it from being created. The body is validated only once the template is
+// merged in, since the template may supply required fields the body leaves
+// out. Projects not made from a template always start out active.
+func newProjectFields(a *app.App, template *Template, body map[string]interface{}) (Fields, []string, error) {
+	doc := body
+	if template != nil {
+		doc = map[string]interface{}{}
+		data, err := json.Marshal(template.Fields)
+		if err != nil {
+			return Fields{}, nil, err
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return Fields{}, nil, err
+		}
+		for k, v := range body {
+			doc[k] = v
+		}
+	}
+
+	errs, err := a.ValidateDocument("project", doc)
+	if err != nil || len(errs) > 0 {
+		return Fields{}, errs, err
+	}
+
+	var fields Fields
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return Fields{}, nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Fields{}, nil, err
+	}
+	if template == nil {
+		fields.Status = "active"
+	}
+	return fields, nil, nil
+}
+
+// maxNameLength is the size of the projects.name column.
+const maxNameLength = 255
+
+// truncateName shortens a generated name to fit the name column, counting
+// characters rather than bytes as Postgres does.
+func truncateName(name string) string {
+	if utf8.RuneCountInString(name) <= maxNameLength {
+		return name
+	}
+	return string([]rune(name)[:maxNameLength])
+}
+
+func insertProject(db *sql.DB, userID string, f Fields) (Project, error) {
+	var p Project
+	err := scanProject(db.QueryRow(`INSERT INTO projects 
+		(user_id, name, repo_url, site_url, description, dependencies, dev_dependencies, status)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING `+projectColumns,
+		userID, f.Name, f.RepoURL, f.SiteURL,
+		f.Description, pq.Array(f.Dependencies), pq.Array(f.DevDependencies), f.Status,
+	), &p)
+	return p, err
+}
+
+func (p Project) Fields() Fields {
+	return Fields{
+		Name:            p.Name,
+		RepoURL:         p.RepoURL,
+		SiteURL:         p.SiteURL,
+		Description:     p.Description,
+		Dependencies:    p.Dependencies,
+		DevDependencies: p.DevDependencies,
+		Status:          p.Status,
+	}
+}
+
+// changedColumns lists the columns whose value differs between old and new,
+// with the value to store for each, so a patch only writes what it changed.
+func changedColumns(old, new Fields) ([]string, []interface{}) {
+	var cols []string
+	var vals []interface{}
+	add := func(changed bool, col string, val interface{}) {
+		if changed {
+			cols = append(cols, col)
+			vals = append(vals, val)
+		}
+	}
+
+	add(old.Name != new.Name, "name", new.Name)
+	add(old.RepoURL != new.RepoURL, "repo_url", new.RepoURL)
+	add(old.SiteURL != new.SiteURL, "site_url", new.SiteURL)
+	add(old.Description != new.Description, "description", new.Description)
+	add(!slices.Equal(old.Dependencies, new.Dependencies), "dependencies", pq.Array(new.Dependencies))
+	add(!slices.Equal(old.DevDependencies, new.DevDependencies), "dev_dependencies", pq.Array(new.DevDependencies))
+	add(old.Status != new.Status, "status", new.Status)
+
+	return cols, vals
+}
+
+// diffFields describes every field that differs between old and new, by its
+// JSON name, for the project's activity history.
+func diffFields(old, new Fields) map[string]activity.Change {
+	changes := map[string]activity.Change{}
+	add := func(changed bool, field string, o, n interface{}) {
+		if changed {
+			changes[field] = activity.Change{Old: o, New: n}
+		}
+	}
+
+	add(old.Name != new.Name, "name", old.Name, new.Name)
+	add(old.RepoURL != new.RepoURL, "repo_url", old.RepoURL, new.RepoURL)
+	add(old.SiteURL != new.SiteURL, "site_url", old.SiteURL, new.SiteURL)
+	add(old.Description != new.Description, "description", old.Description, new.Description)
+	add(!slices.Equal(old.Dependencies, new.Dependencies), "dependencies", old.Dependencies, new.Dependencies)
+	add(!slices.Equal(old.DevDependencies, new.DevDependencies), "dev_dependencies", old.DevDependencies, new.DevDependencies)
+	add(old.Status != new.Status, "status", old.Status, new.Status)
+
+	return changes
+}
+
+func recordActivity(a *app.App, actorID, action, projectID string, changes map[string]activity.Change) {
+	activity.Record(a.DB, activity.Event{
+		ProjectID:  projectID,
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: activity.EntityProject,
+		EntityID:   projectID,
+		Changes:    changes,
+	})
+}
+
+// applyPatch applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// document, depending on mediaType, to the project's editable fields.
+func applyPatch(a *app.App, current Fields, mediaType string, patch []byte) (Fields, error) {
+	doc, err := json.Marshal(patchTarget(current))
+	if err != nil {
+		return current, err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case mediaTypeMergePatch:
+		var body interface{}
+		if err := json.Unmarshal(patch, &body); err != nil {
+			return current, &patchError{http.StatusBadRequest, "Invalid JSON body"}
+		}
+		errs, err := a.ValidateDocument("project_patch", body)
+		if err != nil {
+			return current, err
+		}
+		if len(errs) > 0 {
+			return current, &patchError{http.StatusBadRequest, strings.Join(errs, ", ")}
+		}
+		if patched, err = jsonpatch.MergePatch(doc, patch); err != nil {
+			return current, &patchError{http.StatusBadRequest, "Invalid merge patch"}
+		}
+	case mediaTypeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return current, &patchError{http.StatusBadRequest, "Invalid JSON Patch document"}
+		}
+		if patched, err = ops.Apply(doc); err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return current, &patchError{http.StatusConflict, err.Error()}
+			}
+			return current, &patchError{http.StatusUnprocessableEntity, err.Error()}
+		}
+	default:
+		return current, &patchError{http.StatusUnsupportedMediaType,
+			"Content-Type must be " + mediaTypeMergePatch + " or " + mediaTypeJSONPatch}
+	}
+
+	// The patched document must still be a valid project.
+	var result map[string]interface{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return current, &patchError{http.StatusUnprocessableEntity, "Patch must produce a JSON object"}
+	}
+	for k, v := range result {
+		if isEmptyValue(v) {
+			delete(result, k)
+		}
+	}
+	errs, err := a.ValidateDocument("project", result)
+	if err != nil {
+		return current, err
+	}
+	if len(errs) > 0 {
+		return current, &patchError{http.StatusBadRequest, strings.Join(errs, ", ")}
+	}
+
+	var fields Fields
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return current, &patchError{http.StatusBadRequest, "Invalid payload"}
+	}
+	return fields, nil
+}
+
+// patchTarget is the document a patch is applied to. Unlike the JSON form
+// of Fields it carries every field, empty ones included, so a JSON Patch can
+// replace or append to a field that is currently unset.
+func patchTarget(f Fields) map[string]interface{} {
+	list := func(s []string) []string {
+		if s == nil {
+			return []string{}
+		}
+		return s
+	}
+	return map[string]interface{}{
+		"name":             f.Name,
+		"repo_url":         f.RepoURL,
+		"site_url":         f.SiteURL,
+		"description":      f.Description,
+		"dependencies":     list(f.Dependencies),
+		"dev_dependencies": list(f.DevDependencies),
+		"status":           f.Status,
+	}
+}
+
+// isEmptyValue reports whether a field of a patched document holds nothing,
+// in which case it is left out of validation just as it is when unset.
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// patchError is a patch the client got wrong, as opposed to a server failure.
+type patchError struct {
+	Code    int
+	Message string
+}
+
+func (e *patchError) Error() string {
+	return e.Message
+}
+
+// PurgeTrash permanently deletes every project that has been in the trash for
+// longer than retention, together with an audit entry listing them, and
+// returns the IDs of the removed projects.
+func PurgeTrash(db *sql.DB, retention time.Duration) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`DELETE FROM projects WHERE deleted_at < $1 RETURNING id`, time.Now().Add(-retention))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	err = audit.RecordTx(tx, nil, audit.Entry{Action: audit.ActionProjectPurged, TargetType: "project"},
+		map[string]interface{}{"projects": ids})
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// StartTrashPurger runs PurgeTrash, and then collects the blobs the purged
+// projects' attachments leave unused, every interval for the lifetime of
+// the process.
+func StartTrashPurger(a *app.App) {
+	go func() {
+		ticker := time.NewTicker(a.TrashPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ids, err := PurgeTrash(a.DB, a.TrashRetention)
+			if err != nil {
+				log.Println("Trash purge failed:", err)
+				continue
+			}
+			if len(ids) > 0 {
+				log.Printf("Purged %d projects from trash\n", len(ids))
+			}
+
+			// Purging cascades to attachments but not to the blobs they
+			// shared; this also catches blobs whose deletion failed earlier.
+			n, err := attachment.CollectGarbage(context.Background(), a.DB, a.Storage)
+			if err != nil {
+				log.Println("Blob garbage collection failed:", err)
+			} else if n > 0 {
+				log.Printf("Deleted %d unused blobs\n", n)
+			}
+		}
+	}()
+}
+
+const templateColumns = `id, user_id, name, description, fields, shared, created_at, updated_at`
+
+func scanTemplate(s scanner, t *Template) error {
+	var fields []byte
+	if err := s.Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &fields, &t.Shared, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(fields, &t.Fields)
+}
+
+// visibleTemplate loads a template if it belongs to the user or has been
+// shared.
+func visibleTemplate(db *sql.DB, id, userID string) (Template, error) {
+	var t Template
+	err := scanTemplate(db.QueryRow(`SELECT `+templateColumns+` FROM project_templates
+		WHERE id=$1 AND (user_id=$2 OR shared)`, id, userID), &t)
+	return t, err
+}
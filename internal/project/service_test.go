@@ -0,0 +1,229 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// testApp returns an App with the repository's schemas loaded, which is all
+// the validation paths need.
+func testApp(t *testing.T) *app.App {
+	t.Helper()
+	paths, err := filepath.Glob("../../schemas/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no schemas found: %v", err)
+	}
+	schemas := map[string]string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Base(path)
+		schemas[name[:len(name)-len(".json")]] = string(data)
+	}
+	return &app.App{Schemas: schemas}
+}
+
+func TestPatchTargetHasEveryField(t *testing.T) {
+	doc := patchTarget(Fields{Name: "kanban", Status: "active"})
+	for _, key := range []string{"name", "repo_url", "site_url", "description", "dependencies", "dev_dependencies", "status"} {
+		if _, ok := doc[key]; !ok {
+			t.Errorf("patch target is missing %q", key)
+		}
+	}
+	if deps, ok := doc["dependencies"].([]string); !ok || deps == nil {
+		t.Errorf("empty dependencies should be an empty list, got %#v", doc["dependencies"])
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	a := testApp(t)
+	// A project as POST /projects creates it.
+	current := Fields{Name: "kanban", Status: "active"}
+
+	tests := []struct {
+		name      string
+		mediaType string
+		patch     string
+		want      Fields
+		code      int
+	}{
+		{
+			name:      "merge patch on a new project",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"description": "A board"}`,
+			want:      Fields{Name: "kanban", Description: "A board", Status: "active"},
+		},
+		{
+			name:      "merge patch clears a field with null",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"description": null, "status": "done"}`,
+			want:      Fields{Name: "kanban", Status: "done"},
+		},
+		{
+			name:      "json patch replaces an empty field",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op": "replace", "path": "/description", "value": "A board"}]`,
+			want:      Fields{Name: "kanban", Description: "A board", Status: "active"},
+		},
+		{
+			name:      "json patch appends to an empty list",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op": "add", "path": "/dependencies/-", "value": "react"}]`,
+			want:      Fields{Name: "kanban", Dependencies: []string{"react"}, Status: "active"},
+		},
+		{
+			name:      "json patch test that fails",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op": "test", "path": "/name", "value": "other"}]`,
+			code:      http.StatusConflict,
+		},
+		{
+			name:      "json patch on a path that does not exist",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op": "replace", "path": "/owner", "value": "1"}]`,
+			code:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "unknown status",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"status": "shipped"}`,
+			code:      http.StatusBadRequest,
+		},
+		{
+			name:      "removing the name",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op": "remove", "path": "/name"}]`,
+			code:      http.StatusBadRequest,
+		},
+		{
+			name:      "unsupported media type",
+			mediaType: "application/json",
+			patch:     `{"description": "A board"}`,
+			code:      http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(a, current, tt.mediaType, []byte(tt.patch))
+			if tt.code != 0 {
+				var perr *patchError
+				if !errors.As(err, &perr) || perr.Code != tt.code {
+					t.Fatalf("got error %v, want status %d", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if cols, _ := changedColumns(tt.want, got); len(cols) > 0 {
+				t.Errorf("got %+v, want %+v (differs in %v)", got, tt.want, cols)
+			}
+		})
+	}
+}
+
+func TestApplyPatchLeavesUntouchedFieldsAlone(t *testing.T) {
+	current := Fields{Name: "kanban", Dependencies: []string{"react"}, Status: "developing"}
+	got, err := applyPatch(testApp(t), current, mediaTypeMergePatch, []byte(`{"name": "board"}`))
+	if err != nil {
+		t.Fatalf("applyPatch: %v", err)
+	}
+	cols, _ := changedColumns(current, got)
+	if !slices.Equal(cols, []string{"name"}) {
+		t.Errorf("patch changed %v, want only name", cols)
+	}
+}
+
+// templateRow stands in for the project_templates row SaveTemplate inserts.
+type templateRow struct {
+	fields []byte
+}
+
+func (r templateRow) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = "1"
+	*dest[1].(*string) = "1"
+	*dest[2].(*string) = "Starter"
+	*dest[3].(*string) = ""
+	*dest[4].(*[]byte) = r.fields
+	*dest[5].(*bool) = false
+	*dest[6].(*time.Time) = time.Now()
+	*dest[7].(*time.Time) = time.Now()
+	return nil
+}
+
+func TestCreateFromTemplateOfPostedProject(t *testing.T) {
+	a := testApp(t)
+
+	posted, errs, err := newProjectFields(a, nil, map[string]interface{}{
+		"name":         "kanban",
+		"status":       "done",
+		"dependencies": []interface{}{"react"},
+	})
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("creating the source project: %v %v", err, errs)
+	}
+	if posted.Status != "active" {
+		t.Fatalf("new project has status %q, want active", posted.Status)
+	}
+
+	// What SaveTemplate stores for the project and reads back.
+	stored, _ := json.Marshal(Project{Name: posted.Name, Dependencies: posted.Dependencies, Status: posted.Status}.Fields())
+	var template Template
+	if err := scanTemplate(templateRow{stored}, &template); err != nil {
+		t.Fatalf("scanTemplate: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want Fields
+	}{
+		{
+			name: "empty body",
+			body: map[string]interface{}{},
+			want: Fields{Name: "kanban", Dependencies: []string{"react"}, Status: "active"},
+		},
+		{
+			name: "body overrides the template",
+			body: map[string]interface{}{"name": "board", "status": "backlog"},
+			want: Fields{Name: "board", Dependencies: []string{"react"}, Status: "backlog"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs, err := newProjectFields(a, &template, tt.body)
+			if err != nil || len(errs) > 0 {
+				t.Fatalf("newProjectFields: %v %v", err, errs)
+			}
+			if cols, _ := changedColumns(tt.want, got); len(cols) > 0 {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProjectFieldsValidatesMergedDocument(t *testing.T) {
+	a := testApp(t)
+
+	if _, errs, _ := newProjectFields(a, nil, map[string]interface{}{}); len(errs) == 0 {
+		t.Error("empty body without a template was accepted")
+	}
+
+	template := &Template{Fields: Fields{Name: "kanban", Status: "active"}}
+	if _, errs, _ := newProjectFields(a, template, map[string]interface{}{"owner": "1"}); len(errs) == 0 {
+		t.Error("unknown property in the body was accepted")
+	}
+	if _, errs, _ := newProjectFields(a, template, map[string]interface{}{"status": "shipped"}); len(errs) == 0 {
+		t.Error("unknown status in the body was accepted")
+	}
+}
@@ -0,0 +1,176 @@
+package project
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/audit"
+)
+
+// SaveTemplate godoc
+// @Summary Save a project as a template
+// @Description Store the fields of a project as a template that new projects can be created from
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param template body TemplateRequest true "Template name, description and sharing"
+// @Success 201 {object} Template
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/template [post]
+func SaveTemplate(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var req TemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		var p Project
+		err := scanProject(a.DB.QueryRow(`SELECT `+projectColumns+`
+			FROM projects WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL`, id, claims.ID), &p)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+
+		fields, _ := json.Marshal(p.Fields())
+		var t Template
+		err = scanTemplate(a.DB.QueryRow(`INSERT INTO project_templates (user_id, name, description, fields, shared)
+			VALUES ($1,$2,$3,$4,$5) RETURNING `+templateColumns,
+			claims.ID, req.Name, req.Description, fields, req.Shared), &t)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to create template")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// GetTemplates godoc
+// @Summary List project templates
+// @Description List the authenticated user's templates and the templates other users have shared
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Success 200 {array} Template
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /templates [get]
+func GetTemplates(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+		rows, err := a.DB.Query(`SELECT `+templateColumns+` FROM project_templates
+			WHERE user_id=$1 OR shared ORDER BY name, id`, claims.ID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch templates")
+			return
+		}
+		defer rows.Close()
+
+		templates := []Template{}
+		for rows.Next() {
+			var t Template
+			if err := scanTemplate(rows, &t); err != nil {
+				app.RespondWithError(w, http.StatusInternalServerError, "Scan error")
+				return
+			}
+			templates = append(templates, t)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(templates)
+	}
+}
+
+// GetTemplate godoc
+// @Summary Get a project template
+// @Description Retrieve a template owned by or shared with the authenticated user
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} Template
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /templates/{id} [get]
+func GetTemplate(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		t, err := visibleTemplate(a.DB, id, claims.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Template not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// DeleteTemplate godoc
+// @Summary Delete a project template
+// @Description Delete a template owned by the authenticated user. Projects created from it are not affected.
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 204
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /templates/{id} [delete]
+func DeleteTemplate(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var owner string
+		err := a.DB.QueryRow("SELECT user_id FROM project_templates WHERE id=$1", id).Scan(&owner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Template not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized to delete")
+			return
+		}
+
+		err = app.WithTx(a.DB, func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM project_templates WHERE id=$1 AND user_id=$2", id, claims.ID); err != nil {
+				return err
+			}
+			return audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionTemplateDeleted, TargetType: "template", TargetID: id}, nil)
+		})
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
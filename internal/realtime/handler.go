@@ -0,0 +1,211 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 1024
+	sendBuffer     = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Sockets authenticate with a bearer token rather than cookies, so a
+	// page on another origin gains nothing by opening one.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userID string
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+// deliver queues msg without blocking. A client that falls this far behind
+// is disconnected rather than allowed to hold up everyone else.
+func (c *client) deliver(msg []byte) {
+	select {
+	case c.send <- msg:
+	default:
+		c.stop()
+	}
+}
+
+func (c *client) stop() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func (c *client) reply(msg Message) {
+	data, _ := json.Marshal(msg)
+	c.deliver(data)
+}
+
+// Serve godoc
+// @Summary Subscribe to live project events
+// @Description Upgrade to a WebSocket that pushes the activity events of subscribed projects as they happen. Send {"type":"subscribe","project":"<id>"} to follow a project you own and {"type":"unsubscribe","project":"<id>"} to stop. The server sends unsubscribed when a project is deleted or transferred away. A resync message means events may have been missed and state should be refetched. The connection is closed when the token expires. Browsers, which cannot set headers on a WebSocket, may pass the token in the token query parameter.
+// @Tags realtime
+// @Param token query string false "JWT, when the Authorization header cannot be set"
+// @Success 101
+// @Failure 401 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /ws [get]
+func Serve(a *app.App, hub *Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+			token = strings.TrimPrefix(authHeader, "Bearer ")
+		}
+		if token == "" {
+			app.RespondWithError(w, http.StatusUnauthorized, "No authorization token provided")
+			return
+		}
+		claims, err := a.ParseToken(token)
+		if err != nil {
+			app.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		// Upgrade has already answered the request if it fails.
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		c := &client{
+			hub:    hub,
+			conn:   conn,
+			userID: claims.ID,
+			send:   make(chan []byte, sendBuffer),
+			done:   make(chan struct{}),
+		}
+		hub.register(c)
+
+		var expires time.Time
+		if claims.ExpiresAt != nil {
+			expires = claims.ExpiresAt.Time
+		}
+		go c.writePump(expires)
+		c.readPump(a.DB)
+	}
+}
+
+// readPump handles subscription requests until the connection fails or the
+// client is stopped.
+func (c *client) readPump(db *sql.DB) {
+	defer func() {
+		c.hub.unregister(c)
+		c.stop()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(Message{Type: MessageError, Message: "Invalid message"})
+			continue
+		}
+
+		switch msg.Type {
+		case MessageSubscribe, MessageUnsubscribe:
+			// Ids are keyed in their canonical form so "007" and "7" name
+			// the same subscription as the notifications do.
+			id, err := strconv.ParseInt(msg.Project, 10, 64)
+			if err != nil {
+				c.reply(Message{Type: MessageError, Project: msg.Project, Message: "Invalid project id"})
+				continue
+			}
+			project := strconv.FormatInt(id, 10)
+			if msg.Type == MessageUnsubscribe {
+				c.hub.unsubscribe(c, project)
+				c.reply(Message{Type: MessageUnsubscribed, Project: project})
+				continue
+			}
+			var owner string
+			err = db.QueryRow("SELECT user_id FROM projects WHERE id=$1 AND deleted_at IS NULL", id).Scan(&owner)
+			switch {
+			case err == sql.ErrNoRows:
+				c.reply(Message{Type: MessageError, Project: project, Message: "Project not found"})
+			case err != nil:
+				c.reply(Message{Type: MessageError, Project: project, Message: "Error checking ownership"})
+			case owner != c.userID:
+				c.reply(Message{Type: MessageError, Project: project, Message: "Not authorized"})
+			default:
+				c.hub.subscribe(c, project)
+				c.reply(Message{Type: MessageSubscribed, Project: project})
+			}
+		default:
+			c.reply(Message{Type: MessageError, Message: "Unknown message type"})
+		}
+	}
+}
+
+// writePump is the only writer to the connection. It sends queued messages
+// and pings, and closes the connection once the client is stopped or its
+// token expires.
+func (c *client) writePump(expires time.Time) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	var expired <-chan time.Time
+	if !expires.IsZero() {
+		timer := time.NewTimer(time.Until(expires))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.stop()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.stop()
+				return
+			}
+		case <-expired:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "Token expired"), time.Now().Add(writeWait))
+			c.stop()
+			return
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
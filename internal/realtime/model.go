@@ -0,0 +1,30 @@
+package realtime
+
+import "encoding/json"
+
+const (
+	MessageSubscribe    = "subscribe"
+	MessageUnsubscribe  = "unsubscribe"
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageEvent        = "event"
+	MessageResync       = "resync"
+	MessageError        = "error"
+)
+
+// Message is the envelope of everything sent over the socket in either
+// direction. Clients send subscribe and unsubscribe; the server answers
+// with subscribed or error and pushes event and resync messages, and
+// unsubscribed when a client loses access to a project.
+type Message struct {
+	Type    string          `json:"type"`
+	Project string          `json:"project,omitempty"`
+	Event   json.RawMessage `json:"event,omitempty" swaggertype:"object"`
+	Message string          `json:"message,omitempty"`
+}
+
+// notification is the part of an activity_events notification the hub
+// needs to route it. The payload is forwarded to clients untouched.
+type notification struct {
+	Project json.Number `json:"project"`
+}
@@ -0,0 +1,164 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// Channel is the Postgres notification channel activity events are
+// published on by the trigger on activity_events.
+const Channel = "activity_events"
+
+// Hub tracks which connected clients are subscribed to which projects and
+// delivers notifications to them. Each server instance has its own hub;
+// Postgres LISTEN/NOTIFY carries events between instances.
+type Hub struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	subs    map[string]map[*client]struct{}
+	clients map[*client]struct{}
+}
+
+func NewHub(db *sql.DB) *Hub {
+	return &Hub{
+		db:      db,
+		subs:    map[string]map[*client]struct{}{},
+		clients: map[*client]struct{}{},
+	}
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = struct{}{}
+}
+
+// unregister drops the client and all its subscriptions.
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+	for project, subs := range h.subs {
+		delete(subs, c)
+		if len(subs) == 0 {
+			delete(h.subs, project)
+		}
+	}
+}
+
+func (h *Hub) subscribe(c *client, project string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[project] == nil {
+		h.subs[project] = map[*client]struct{}{}
+	}
+	h.subs[project][c] = struct{}{}
+}
+
+func (h *Hub) unsubscribe(c *client, project string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[project], c)
+	if len(h.subs[project]) == 0 {
+		delete(h.subs, project)
+	}
+}
+
+// dispatch forwards a notification payload to every client subscribed to
+// its project. Access is checked again for every event, since the project
+// may have changed hands or been deleted since the client subscribed;
+// clients that lost it get the event only if they still own the project
+// and are then unsubscribed.
+func (h *Hub) dispatch(payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		log.Println("Ignoring malformed activity notification:", err)
+		return
+	}
+	project := n.Project.String()
+	// Most notifications concern projects nobody on this instance watches,
+	// so the ownership query is only made when there is someone to check.
+	h.mu.Lock()
+	watched := len(h.subs[project]) > 0
+	h.mu.Unlock()
+	if !watched {
+		return
+	}
+	msg, _ := json.Marshal(Message{Type: MessageEvent, Project: project, Event: json.RawMessage(payload)})
+
+	// A purged project is not found at all, which leaves owner empty and
+	// drops every subscriber.
+	var owner string
+	var trashed bool
+	err := h.db.QueryRow(`SELECT user_id, deleted_at IS NOT NULL FROM projects WHERE id=$1`, project).
+		Scan(&owner, &trashed)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Checking access to project %s failed: %v", project, err)
+		return
+	}
+	revoked, _ := json.Marshal(Message{Type: MessageUnsubscribed, Project: project, Message: "Project is no longer accessible"})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.subs[project] {
+		if c.userID == owner {
+			c.deliver(msg)
+		}
+		if c.userID != owner || trashed {
+			delete(h.subs[project], c)
+			c.deliver(revoked)
+		}
+	}
+	if len(h.subs[project]) == 0 {
+		delete(h.subs, project)
+	}
+}
+
+// resync tells every client that events may have been missed, after the
+// connection to Postgres was lost and re-established.
+func (h *Hub) resync() {
+	msg, _ := json.Marshal(Message{Type: MessageResync})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		c.deliver(msg)
+	}
+}
+
+// StartListener listens for activity notifications on a dedicated
+// connection for the lifetime of the process and hands them to the hub.
+func StartListener(hub *Hub) {
+	listener := pq.NewListener(app.ConnString(), 10*time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Println("Activity listener:", err)
+			}
+		})
+	if err := listener.Listen(Channel); err != nil {
+		log.Fatal("Activity listener setup failed:", err)
+	}
+
+	go func() {
+		for {
+			select {
+			case n := <-listener.Notify:
+				// A nil notification means the connection was re-established
+				// and anything sent in between is gone.
+				if n == nil {
+					hub.resync()
+					continue
+				}
+				hub.dispatch(n.Extra)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+}
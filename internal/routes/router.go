@@ -6,12 +6,20 @@ import (
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 
+	"github.com/nihsioK/go-kanban/internal/activity"
 	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/attachment"
+	"github.com/nihsioK/go-kanban/internal/audit"
 	"github.com/nihsioK/go-kanban/internal/project"
+	"github.com/nihsioK/go-kanban/internal/realtime"
+	"github.com/nihsioK/go-kanban/internal/search"
+	"github.com/nihsioK/go-kanban/internal/tag"
+	"github.com/nihsioK/go-kanban/internal/transfer"
 	"github.com/nihsioK/go-kanban/internal/user"
+	"github.com/nihsioK/go-kanban/internal/view"
 )
 
-func SetupRouter(a *app.App) *mux.Router {
+func SetupRouter(a *app.App, hub *realtime.Hub) *mux.Router {
 	r := mux.NewRouter()
 
 	// Swagger docs
@@ -22,6 +30,25 @@ func SetupRouter(a *app.App) *mux.Router {
 	r.Handle("/login", a.Logging(user.Login(a))).Methods("POST")
 
 	// Protected routes
+	r.Handle("/search", a.Logging(a.JWTAuth(search.Search(a)))).Methods("GET")
+	r.Handle("/trash", a.Logging(a.JWTAuth(project.Trash(a)))).Methods("GET")
+	r.Handle("/ws", a.Logging(realtime.Serve(a, hub))).Methods("GET")
+
+	adminRouter := r.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(a.Logging)
+	adminRouter.Use(a.JWTAuth)
+	adminRouter.Use(a.RequireAdmin)
+
+	adminRouter.Handle("/audit", http.HandlerFunc(audit.GetAll(a))).Methods("GET")
+
+	meRouter := r.PathPrefix("/me").Subrouter()
+	meRouter.Use(a.Logging)
+	meRouter.Use(a.JWTAuth)
+
+	meRouter.Handle("/password", a.Validate("password", user.ChangePassword(a))).Methods("PUT")
+	meRouter.Handle("/transfers", http.HandlerFunc(transfer.GetPending(a))).Methods("GET")
+	meRouter.Handle("/feed", http.HandlerFunc(activity.GetFeed(a))).Methods("GET")
+
 	projectRouter := r.PathPrefix("/projects").Subrouter()
 	projectRouter.Use(a.Logging)
 	projectRouter.Use(a.JWTAuth)
@@ -29,8 +56,60 @@ func SetupRouter(a *app.App) *mux.Router {
 	projectRouter.Handle("", http.HandlerFunc(project.GetAll(a))).Methods("GET")
 	projectRouter.Handle("/{id}", http.HandlerFunc(project.GetOne(a))).Methods("GET")
 	projectRouter.Handle("/{id}", http.HandlerFunc(project.Delete(a))).Methods("DELETE")
-	projectRouter.Handle("", a.Validate("project", project.Create(a))).Methods("POST")
+	projectRouter.Handle("", http.HandlerFunc(project.Create(a))).Methods("POST")
 	projectRouter.Handle("/{id}", a.Validate("project", project.Update(a))).Methods("PUT")
+	projectRouter.Handle("/{id}", http.HandlerFunc(project.Patch(a))).Methods("PATCH")
+	projectRouter.Handle("/{id}/restore", http.HandlerFunc(project.Restore(a))).Methods("POST")
+	projectRouter.Handle("/{id}/archive", http.HandlerFunc(project.Archive(a))).Methods("POST")
+	projectRouter.Handle("/{id}/unarchive", http.HandlerFunc(project.Unarchive(a))).Methods("POST")
+	projectRouter.Handle("/{id}/clone", http.HandlerFunc(project.Clone(a))).Methods("POST")
+	projectRouter.Handle("/{id}/template", a.Validate("template", project.SaveTemplate(a))).Methods("POST")
+	projectRouter.Handle("/{id}/transfers", http.HandlerFunc(transfer.GetProjectHistory(a))).Methods("GET")
+	projectRouter.Handle("/{id}/transfers", a.Validate("transfer", transfer.Create(a))).Methods("POST")
+	projectRouter.Handle("/{id}/tags/{tagId}", http.HandlerFunc(tag.Attach(a))).Methods("PUT")
+	projectRouter.Handle("/{id}/tags/{tagId}", http.HandlerFunc(tag.Detach(a))).Methods("DELETE")
+	projectRouter.Handle("/{id}/activity", http.HandlerFunc(activity.GetProjectActivity(a))).Methods("GET")
+	projectRouter.Handle("/{id}/attachments", http.HandlerFunc(attachment.GetAll(a))).Methods("GET")
+	projectRouter.Handle("/{id}/attachments", http.HandlerFunc(attachment.Upload(a))).Methods("POST")
+	projectRouter.Handle("/{id}/attachments/{attachmentId}", http.HandlerFunc(attachment.Download(a))).Methods("GET")
+	projectRouter.Handle("/{id}/attachments/{attachmentId}", http.HandlerFunc(attachment.Delete(a))).Methods("DELETE")
+
+	tagRouter := r.PathPrefix("/tags").Subrouter()
+	tagRouter.Use(a.Logging)
+	tagRouter.Use(a.JWTAuth)
+
+	tagRouter.Handle("", http.HandlerFunc(tag.GetAll(a))).Methods("GET")
+	tagRouter.Handle("/{id}", http.HandlerFunc(tag.Delete(a))).Methods("DELETE")
+	tagRouter.Handle("", a.Validate("tag", tag.Create(a))).Methods("POST")
+	tagRouter.Handle("/{id}", a.Validate("tag", tag.Update(a))).Methods("PUT")
+	tagRouter.Handle("/{id}/merge", a.Validate("tag_merge", tag.Merge(a))).Methods("POST")
+
+	transferRouter := r.PathPrefix("/transfers").Subrouter()
+	transferRouter.Use(a.Logging)
+	transferRouter.Use(a.JWTAuth)
+
+	transferRouter.Handle("/{id}/accept", http.HandlerFunc(transfer.Accept(a))).Methods("POST")
+	transferRouter.Handle("/{id}/decline", http.HandlerFunc(transfer.Decline(a))).Methods("POST")
+	transferRouter.Handle("/{id}/cancel", http.HandlerFunc(transfer.Cancel(a))).Methods("POST")
+
+	templateRouter := r.PathPrefix("/templates").Subrouter()
+	templateRouter.Use(a.Logging)
+	templateRouter.Use(a.JWTAuth)
+
+	templateRouter.Handle("", http.HandlerFunc(project.GetTemplates(a))).Methods("GET")
+	templateRouter.Handle("/{id}", http.HandlerFunc(project.GetTemplate(a))).Methods("GET")
+	templateRouter.Handle("/{id}", http.HandlerFunc(project.DeleteTemplate(a))).Methods("DELETE")
+
+	viewRouter := r.PathPrefix("/views").Subrouter()
+	viewRouter.Use(a.Logging)
+	viewRouter.Use(a.JWTAuth)
+
+	viewRouter.Handle("", http.HandlerFunc(view.GetAll(a))).Methods("GET")
+	viewRouter.Handle("/{id}", http.HandlerFunc(view.GetOne(a))).Methods("GET")
+	viewRouter.Handle("/{id}/projects", http.HandlerFunc(view.Projects(a))).Methods("GET")
+	viewRouter.Handle("/{id}", http.HandlerFunc(view.Delete(a))).Methods("DELETE")
+	viewRouter.Handle("", a.Validate("view", view.Create(a))).Methods("POST")
+	viewRouter.Handle("/{id}", a.Validate("view", view.Update(a))).Methods("PUT")
 
 	return r
 }
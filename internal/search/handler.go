@@ -0,0 +1,60 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// Search godoc
+// @Summary Full-text search
+// @Description Search the names and descriptions of projects the authenticated user can access. Results are ranked by relevance and carry a snippet with matches wrapped in <mark> tags.
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param q query string true "Search query (websearch syntax: quoted phrases, OR, -exclude)"
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param offset query int false "Number of results to skip" default(0)
+// @Success 200 {object} search.ResultPage
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /search [get]
+func Search(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+		query := r.URL.Query()
+
+		q := strings.TrimSpace(query.Get("q"))
+		if q == "" {
+			app.RespondWithError(w, http.StatusBadRequest, "Missing search query")
+			return
+		}
+
+		limit, err := app.ParseLimit(query)
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		offset := 0
+		if v := query.Get("offset"); v != "" {
+			if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+				app.RespondWithError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+				return
+			}
+		}
+
+		results, err := Projects(a.DB, claims.ID, q, limit, offset)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Search failed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ResultPage{Data: results, Offset: offset, Limit: limit})
+	}
+}
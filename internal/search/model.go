@@ -0,0 +1,16 @@
+package search
+
+type Result struct {
+	Type     string  `json:"type"`
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Snippet  string  `json:"snippet"`
+	Rank     float64 `json:"rank"`
+	Archived bool    `json:"archived"`
+}
+
+type ResultPage struct {
+	Data   []Result `json:"data"`
+	Offset int      `json:"offset"`
+	Limit  int      `json:"limit"`
+}
@@ -0,0 +1,38 @@
+package search
+
+import (
+	"database/sql"
+)
+
+// Projects runs a full-text query over the names and descriptions of the
+// projects the user can access, archived ones included, best matches
+// first. The snippet text is HTML-escaped before highlighting so the only
+// markup in it is <mark>.
+func Projects(db *sql.DB, userID, q string, limit, offset int) ([]Result, error) {
+	rows, err := db.Query(`
+		SELECT id, name,
+			ts_headline('english',
+				replace(replace(replace(coalesce(nullif(description, ''), name), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2'),
+			ts_rank(search_vector, query) AS rank,
+			archived_at IS NOT NULL
+		FROM projects, websearch_to_tsquery('english', $1) AS query
+		WHERE user_id=$2 AND deleted_at IS NULL AND search_vector @@ query
+		ORDER BY rank DESC, id DESC
+		LIMIT $3 OFFSET $4`,
+		q, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		res := Result{Type: "project"}
+		if err := rows.Scan(&res.ID, &res.Title, &res.Snippet, &res.Rank, &res.Archived); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// testRoundTrip puts an object, reads it back, deletes it and checks that it
+// is gone. Every Store has to pass it.
+func testRoundTrip(t *testing.T, store Store) {
+	ctx := context.Background()
+	key := "sha256/ab/roundtrip-" + strconv.FormatInt(int64(os.Getpid()), 10)
+	content := []byte("attachment content")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("Get returned %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+
+	// Deleting what is already gone is not an error, so callers can retry.
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("second Delete: %v", err)
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	testRoundTrip(t, store)
+}
+
+// TestS3RoundTrip runs against the MinIO service from docker-compose, or any
+// other S3-compatible endpoint given in S3_TEST_ENDPOINT.
+func TestS3RoundTrip(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT not set")
+	}
+
+	store, err := NewS3(context.Background(), S3Config{
+		Endpoint:  endpoint,
+		AccessKey: envOr("S3_TEST_ACCESS_KEY", "minioadmin"),
+		SecretKey: envOr("S3_TEST_SECRET_KEY", "minioadmin"),
+		Bucket:    envOr("S3_TEST_BUCKET", "go-kanban-test"),
+		Region:    envOr("S3_TEST_REGION", "us-east-1"),
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	testRoundTrip(t, store)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,328 @@
+package tag
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/nihsioK/go-kanban/internal/activity"
+	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/audit"
+)
+
+// GetAll godoc
+// @Summary List tags
+// @Description List the authenticated user's tags with the number of projects carrying each
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Success 200 {array} Tag
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /tags [get]
+func GetAll(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+		rows, err := a.DB.Query(`SELECT `+tagColumns+` FROM tags WHERE user_id=$1 ORDER BY lower(name)`, claims.ID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch tags")
+			return
+		}
+		defer rows.Close()
+
+		tags := []Tag{}
+		for rows.Next() {
+			var t Tag
+			if err := scanTag(rows, &t); err != nil {
+				app.RespondWithError(w, http.StatusInternalServerError, "Scan error")
+				return
+			}
+			tags = append(tags, t)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tags)
+	}
+}
+
+// Create godoc
+// @Summary Create a tag
+// @Description Create a tag for the authenticated user. Names are unique per user, ignoring case, and cannot contain commas, which separate tags in project filters.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag body Tag true "Tag name and color"
+// @Success 201 {object} Tag
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /tags [post]
+func Create(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var t Tag
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		err := scanTag(a.DB.QueryRow(`INSERT INTO tags (user_id, name, color)
+			VALUES ($1,$2,coalesce(nullif($3, ''), '#cccccc')) RETURNING `+tagColumns,
+			claims.ID, t.Name, t.Color), &t)
+		if err != nil {
+			if isUniqueViolation(err) {
+				app.RespondWithError(w, http.StatusConflict, "A tag with this name already exists")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Failed to create tag")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// Update godoc
+// @Summary Rename or recolor a tag
+// @Description Update a tag. Every project carrying it shows the new name immediately.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param tag body Tag true "Tag name and color"
+// @Success 200 {object} Tag
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /tags/{id} [put]
+func Update(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		var t Tag
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		err := app.WithTx(a.DB, func(tx *sql.Tx) error {
+			var oldName string
+			err := tx.QueryRow(`SELECT name FROM tags WHERE id=$1 AND user_id=$2 FOR UPDATE`, id, claims.ID).Scan(&oldName)
+			if err != nil {
+				return err
+			}
+			err = scanTag(tx.QueryRow(`UPDATE tags SET name=$1, color=coalesce(nullif($2, ''), color)
+				WHERE id=$3 RETURNING `+tagColumns,
+				t.Name, t.Color, id), &t)
+			if err != nil || t.Name == oldName {
+				return err
+			}
+			return bumpTagged(tx, id)
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Tag not found")
+			} else if isUniqueViolation(err) {
+				app.RespondWithError(w, http.StatusConflict, "A tag with this name already exists")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Update failed")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// Delete godoc
+// @Summary Delete a tag
+// @Description Delete a tag and remove it from every project carrying it
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 204
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /tags/{id} [delete]
+func Delete(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		err := app.WithTx(a.DB, func(tx *sql.Tx) error {
+			if err := bumpTagged(tx, id); err != nil {
+				return err
+			}
+			res, err := tx.Exec("DELETE FROM tags WHERE id=$1 AND user_id=$2", id, claims.ID)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return sql.ErrNoRows
+			}
+			return audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionTagDeleted, TargetType: "tag", TargetID: id}, nil)
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Tag not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Merge godoc
+// @Summary Merge a tag into another
+// @Description Move every project carrying the tag onto the target tag and delete it, in one transaction
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the tag to merge away"
+// @Param merge body MergeRequest true "Target tag"
+// @Success 200 {object} Tag
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /tags/{id}/merge [post]
+func Merge(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		var req MergeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		if err := merge(a.DB, r, claims.ID, id, req.Into); err != nil {
+			if errors.Is(err, ErrSameTag) {
+				app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			} else if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Tag not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Merge failed")
+			}
+			return
+		}
+		t, err := getTag(a.DB, req.Into, claims.ID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// Attach godoc
+// @Summary Tag a project
+// @Description Attach one of the authenticated user's tags to one of their projects
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param tagId path string true "Tag ID"
+// @Success 204
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/tags/{tagId} [put]
+func Attach(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		name, err := attach(a.DB, claims.ID, vars["id"], vars["tagId"])
+		if err != nil {
+			respondTaggingError(w, err, "Failed to tag project")
+			return
+		}
+		if name != "" {
+			recordTagging(a, claims.ID, activity.ActionTagged, vars["id"], vars["tagId"],
+				activity.Change{New: name})
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Detach godoc
+// @Summary Untag a project
+// @Description Remove a tag from one of the authenticated user's projects
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param tagId path string true "Tag ID"
+// @Success 204
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/tags/{tagId} [delete]
+func Detach(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		name, err := detach(a.DB, claims.ID, vars["id"], vars["tagId"])
+		if err != nil {
+			if errors.Is(err, ErrTagNotFound) {
+				app.RespondWithError(w, http.StatusNotFound, "Project is not tagged with this tag")
+			} else {
+				respondTaggingError(w, err, "Failed to untag project")
+			}
+			return
+		}
+		recordTagging(a, claims.ID, activity.ActionUntagged, vars["id"], vars["tagId"],
+			activity.Change{Old: name})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// respondTaggingError answers a failed attach or detach.
+func respondTaggingError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case err == sql.ErrNoRows:
+		app.RespondWithError(w, http.StatusNotFound, "Project not found")
+	case errors.Is(err, ErrTagNotFound):
+		app.RespondWithError(w, http.StatusNotFound, "Tag not found")
+	case errors.Is(err, ErrArchived):
+		app.RespondWithError(w, http.StatusConflict, "Project is archived")
+	default:
+		app.RespondWithError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// recordTagging adds a tag being attached to or removed from a project to
+// the project's activity, with the tag's name as the change.
+func recordTagging(a *app.App, actorID, action, projectID, tagID string, name activity.Change) {
+	activity.Record(a.DB, activity.Event{
+		ProjectID:  projectID,
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: activity.EntityTag,
+		EntityID:   tagID,
+		Changes:    map[string]activity.Change{"name": name},
+	})
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,17 @@
+package tag
+
+import "time"
+
+type Tag struct {
+	ID        string    `json:"id,omitempty"`
+	UserID    string    `json:"user,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Color     string    `json:"color,omitempty"`
+	Projects  int       `json:"projects"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MergeRequest struct {
+	Into string `json:"into"`
+}
@@ -0,0 +1,165 @@
+package tag
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/nihsioK/go-kanban/internal/audit"
+)
+
+var (
+	ErrSameTag     = errors.New("cannot merge a tag into itself")
+	ErrArchived    = errors.New("project is archived")
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+const tagColumns = `id, user_id, name, color,
+	(SELECT count(*) FROM project_tags pt WHERE pt.tag_id = tags.id) AS projects,
+	created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTag(s scanner, t *Tag) error {
+	return s.Scan(&t.ID, &t.UserID, &t.Name, &t.Color, &t.Projects, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func getTag(db *sql.DB, id, userID string) (Tag, error) {
+	var t Tag
+	err := scanTag(db.QueryRow(`SELECT `+tagColumns+` FROM tags WHERE id=$1 AND user_id=$2`, id, userID), &t)
+	return t, err
+}
+
+// bumpTagged bumps the version of every project carrying the tag. Tags are
+// part of a project's representation, so its ETag has to change with them.
+func bumpTagged(tx *sql.Tx, tagID string) error {
+	_, err := tx.Exec(`UPDATE projects SET version=version+1
+		WHERE id IN (SELECT project_id FROM project_tags WHERE tag_id=$1)`, tagID)
+	return err
+}
+
+// lockProject locks a live project of the user whose tags are about to
+// change. It returns sql.ErrNoRows if there is none and ErrArchived if the
+// project is read-only.
+func lockProject(tx *sql.Tx, projectID, userID string) error {
+	var archived bool
+	err := tx.QueryRow(`SELECT archived_at IS NOT NULL FROM projects
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL FOR UPDATE`, projectID, userID).Scan(&archived)
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrArchived
+	}
+	return nil
+}
+
+// attach tags a project and returns the tag's name, or an empty name if the
+// project already carried it and nothing changed.
+func attach(db *sql.DB, userID, projectID, tagID string) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if err := lockProject(tx, projectID, userID); err != nil {
+		return "", err
+	}
+
+	var name string
+	var inserted bool
+	err = tx.QueryRow(`WITH tag AS (SELECT id, name FROM tags WHERE id=$2 AND user_id=$3),
+		ins AS (INSERT INTO project_tags (project_id, tag_id) SELECT $1, id FROM tag
+			ON CONFLICT DO NOTHING RETURNING tag_id)
+		SELECT tag.name, EXISTS (SELECT 1 FROM ins) FROM tag`, projectID, tagID, userID).Scan(&name, &inserted)
+	if err == sql.ErrNoRows {
+		return "", ErrTagNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !inserted {
+		return "", nil
+	}
+
+	if _, err := tx.Exec(`UPDATE projects SET version=version+1 WHERE id=$1`, projectID); err != nil {
+		return "", err
+	}
+	return name, tx.Commit()
+}
+
+// detach removes a tag from a project and returns the tag's name. It
+// returns ErrTagNotFound if the project does not carry the tag.
+func detach(db *sql.DB, userID, projectID, tagID string) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if err := lockProject(tx, projectID, userID); err != nil {
+		return "", err
+	}
+
+	var name string
+	err = tx.QueryRow(`DELETE FROM project_tags pt USING tags t
+		WHERE pt.tag_id = t.id AND pt.project_id=$1 AND pt.tag_id=$2 AND t.user_id=$3
+		RETURNING t.name`, projectID, tagID, userID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", ErrTagNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(`UPDATE projects SET version=version+1 WHERE id=$1`, projectID); err != nil {
+		return "", err
+	}
+	return name, tx.Commit()
+}
+
+// merge moves every project tagged with src onto into and deletes src, in
+// one transaction with its audit entry so no project loses its tag halfway
+// through.
+func merge(db *sql.DB, r *http.Request, userID, src, into string) error {
+	if src == into {
+		return ErrSameTag
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRow(`SELECT count(*) FROM (SELECT id FROM tags
+		WHERE id IN ($1, $2) AND user_id=$3 FOR UPDATE) locked`, src, into, userID).Scan(&n); err != nil {
+		return err
+	}
+	if n != 2 {
+		return sql.ErrNoRows
+	}
+
+	if err := bumpTagged(tx, src); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO project_tags (project_id, tag_id)
+		SELECT project_id, $1 FROM project_tags WHERE tag_id=$2
+		ON CONFLICT DO NOTHING`, into, src); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE id=$1`, src); err != nil {
+		return err
+	}
+	err = audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionTagMerged, TargetType: "tag", TargetID: src},
+		map[string]interface{}{"into": into})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,273 @@
+package transfer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/nihsioK/go-kanban/internal/app"
+)
+
+// Create godoc
+// @Summary Request a project ownership transfer
+// @Description Offer a project owned by the authenticated user to another user. Ownership changes only when the recipient accepts.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param transfer body TransferRequest true "Recipient"
+// @Success 201 {object} Transfer
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/transfers [post]
+func Create(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var req TransferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+
+		var owner string
+		var archived bool
+		err := a.DB.QueryRow("SELECT user_id, archived_at IS NOT NULL FROM projects WHERE id=$1 AND deleted_at IS NULL", projectID).
+			Scan(&owner, &archived)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized to transfer")
+			return
+		}
+		if archived {
+			app.RespondWithError(w, http.StatusConflict, "Project is archived")
+			return
+		}
+
+		var toUserID string
+		err = a.DB.QueryRow("SELECT id FROM users WHERE username=$1 AND is_active", req.ToUsername).Scan(&toUserID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "User not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error fetching user")
+			}
+			return
+		}
+		if toUserID == claims.ID {
+			app.RespondWithError(w, http.StatusBadRequest, "Cannot transfer a project to its owner")
+			return
+		}
+
+		var id string
+		err = a.DB.QueryRow(`INSERT INTO project_transfers (project_id, from_user_id, to_user_id)
+			VALUES ($1,$2,$3) RETURNING id`, projectID, claims.ID, toUserID).Scan(&id)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				app.RespondWithError(w, http.StatusConflict, "A transfer of this project is already pending")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Failed to create transfer")
+			}
+			return
+		}
+
+		t, err := getTransfer(a.DB, id)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// GetProjectHistory godoc
+// @Summary List a project's ownership transfers
+// @Description List every transfer of a project owned by the authenticated user, pending and resolved, newest first
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {array} Transfer
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /projects/{id}/transfers [get]
+func GetProjectHistory(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var owner string
+		err := a.DB.QueryRow("SELECT user_id FROM projects WHERE id=$1 AND deleted_at IS NULL", projectID).Scan(&owner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
+			return
+		}
+
+		transfers, err := listTransfers(a.DB, "t.project_id=$1", projectID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch transfers")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transfers)
+	}
+}
+
+// GetPending godoc
+// @Summary List pending transfers
+// @Description List the pending transfers the authenticated user has sent or received
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Success 200 {array} Transfer
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /me/transfers [get]
+func GetPending(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		transfers, err := listTransfers(a.DB, "t.status=$1 AND (t.from_user_id=$2 OR t.to_user_id=$2)",
+			StatusPending, claims.ID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch transfers")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transfers)
+	}
+}
+
+// Accept godoc
+// @Summary Accept a project transfer
+// @Description Take ownership of the project offered in a pending transfer addressed to the authenticated user
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} Transfer
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /transfers/{id}/accept [post]
+func Accept(a *app.App) http.HandlerFunc {
+	return resolveHandler(a, false, func(r *http.Request, id string) error {
+		return accept(a.DB, r, id)
+	})
+}
+
+// Decline godoc
+// @Summary Decline a project transfer
+// @Description Refuse a pending transfer addressed to the authenticated user
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} Transfer
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /transfers/{id}/decline [post]
+func Decline(a *app.App) http.HandlerFunc {
+	return resolveHandler(a, false, func(r *http.Request, id string) error {
+		return resolve(a.DB, id, StatusDeclined)
+	})
+}
+
+// Cancel godoc
+// @Summary Cancel a project transfer
+// @Description Withdraw a pending transfer the authenticated user has sent
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} Transfer
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 409 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /transfers/{id}/cancel [post]
+func Cancel(a *app.App) http.HandlerFunc {
+	return resolveHandler(a, true, func(r *http.Request, id string) error {
+		return resolve(a.DB, id, StatusCancelled)
+	})
+}
+
+// resolveHandler checks that the caller is the transfer's recipient, or its
+// sender when bySender is set, before running action.
+func resolveHandler(a *app.App, bySender bool, action func(r *http.Request, id string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		t, err := getTransfer(a.DB, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "Transfer not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+
+		party := t.ToUserID
+		if bySender {
+			party = t.FromUserID
+		}
+		if party != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
+			return
+		}
+
+		if err := action(r, id); err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				app.RespondWithError(w, http.StatusNotFound, "Project not found")
+			} else if errors.Is(err, ErrNotPending) || errors.Is(err, ErrOwnerChange) || errors.Is(err, ErrArchived) {
+				app.RespondWithError(w, http.StatusConflict, err.Error())
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve transfer")
+			}
+			return
+		}
+
+		if t, err = getTransfer(a.DB, id); err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	}
+}
@@ -0,0 +1,28 @@
+package transfer
+
+import "time"
+
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+)
+
+// Transfer is a request to hand a project over to another user. Resolved
+// transfers are kept as the project's ownership history.
+type Transfer struct {
+	ID           string     `json:"id"`
+	ProjectID    string     `json:"project"`
+	FromUserID   string     `json:"from_user"`
+	FromUsername string     `json:"from_username"`
+	ToUserID     string     `json:"to_user"`
+	ToUsername   string     `json:"to_username"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+}
+
+type TransferRequest struct {
+	ToUsername string `json:"to_username"`
+}
@@ -0,0 +1,136 @@
+package transfer
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/nihsioK/go-kanban/internal/activity"
+	"github.com/nihsioK/go-kanban/internal/audit"
+)
+
+var (
+	ErrNotPending      = errors.New("transfer is no longer pending")
+	ErrOwnerChange     = errors.New("project owner changed since the transfer was requested")
+	ErrArchived        = errors.New("project is archived")
+	ErrProjectNotFound = errors.New("project not found")
+)
+
+const transferSelect = `SELECT t.id, t.project_id, t.from_user_id, fu.username, t.to_user_id, tu.username,
+		t.status, t.created_at, t.resolved_at
+	FROM project_transfers t
+	JOIN users fu ON fu.id = t.from_user_id
+	JOIN users tu ON tu.id = t.to_user_id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransfer(s scanner, t *Transfer) error {
+	return s.Scan(&t.ID, &t.ProjectID, &t.FromUserID, &t.FromUsername, &t.ToUserID, &t.ToUsername,
+		&t.Status, &t.CreatedAt, &t.ResolvedAt)
+}
+
+func getTransfer(db *sql.DB, id string) (Transfer, error) {
+	var t Transfer
+	err := scanTransfer(db.QueryRow(transferSelect+` WHERE t.id=$1`, id), &t)
+	return t, err
+}
+
+func listTransfers(db *sql.DB, where string, args ...interface{}) ([]Transfer, error) {
+	rows, err := db.Query(transferSelect+` WHERE `+where+` ORDER BY t.created_at DESC, t.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []Transfer{}
+	for rows.Next() {
+		var t Transfer
+		if err := scanTransfer(rows, &t); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// accept hands the project over to the recipient. The transfer and the
+// project are locked and updated in one transaction so the ownership change,
+// its record in project_transfers and its audit entry can never disagree.
+func accept(db *sql.DB, r *http.Request, id string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var projectID, fromUserID, toUserID, status string
+	err = tx.QueryRow(`SELECT project_id, from_user_id, to_user_id, status
+		FROM project_transfers WHERE id=$1 FOR UPDATE`, id).Scan(&projectID, &fromUserID, &toUserID, &status)
+	if err != nil {
+		return err
+	}
+	if status != StatusPending {
+		return ErrNotPending
+	}
+
+	var owner string
+	var archived bool
+	err = tx.QueryRow(`SELECT user_id, archived_at IS NOT NULL FROM projects
+		WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, projectID).Scan(&owner, &archived)
+	if err == sql.ErrNoRows {
+		return ErrProjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrArchived
+	}
+	if owner != fromUserID {
+		return ErrOwnerChange
+	}
+
+	if _, err := tx.Exec(`UPDATE projects SET user_id=$1 WHERE id=$2`, toUserID, projectID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE project_transfers SET status=$1, resolved_at=CURRENT_TIMESTAMP
+		WHERE id=$2`, StatusAccepted, id); err != nil {
+		return err
+	}
+
+	err = audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionOwnerChanged, TargetType: "project", TargetID: projectID},
+		map[string]interface{}{"transfer": id, "from_user": fromUserID, "to_user": toUserID})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	activity.Record(db, activity.Event{
+		ProjectID:  projectID,
+		ActorID:    toUserID,
+		Action:     activity.ActionTransferred,
+		EntityType: activity.EntityProject,
+		EntityID:   projectID,
+		Changes:    map[string]activity.Change{"user": {Old: fromUserID, New: toUserID}},
+	})
+	log.Printf("Project %s transferred from user %s to user %s (transfer %s)\n", projectID, fromUserID, toUserID, id)
+	return nil
+}
+
+// resolve closes a pending transfer without changing ownership.
+func resolve(db *sql.DB, id, status string) error {
+	res, err := db.Exec(`UPDATE project_transfers SET status=$1, resolved_at=CURRENT_TIMESTAMP
+		WHERE id=$2 AND status=$3`, status, id, StatusPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotPending
+	}
+	return nil
+}
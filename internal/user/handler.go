@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/audit"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,6 +19,7 @@ import (
 // @Param user body user.Credentials true "User credentials"
 // @Success 200 {object} user.UserResponse
 // @Failure 400 {object} app.ErrorResponse
+// @Failure 422 {object} app.ErrorResponse
 // @Router /register [post]
 func Register(a *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -27,6 +29,16 @@ func Register(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		violations, err := a.PasswordPolicy.Check(creds.Username, creds.Password)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Error checking password")
+			return
+		}
+		if len(violations) > 0 {
+			app.RespondWithErrorDetails(w, http.StatusUnprocessableEntity, "Password does not meet policy", violations)
+			return
+		}
+
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(creds.Password), 8)
 		if err != nil {
 			app.RespondWithError(w, http.StatusInternalServerError, "Error hashing password")
@@ -47,6 +59,8 @@ func Register(a *app.App) http.HandlerFunc {
 			app.RespondWithError(w, http.StatusInternalServerError, "Error generating token")
 			return
 		}
+		audit.Record(a.DB, r, audit.Entry{ActorID: id, ActorUsername: creds.Username, Action: audit.ActionTokenCreated,
+			TargetType: "user", TargetID: id}, nil)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(UserResponse{ID: id, Username: creds.Username, Token: token})
 	}
@@ -78,6 +92,8 @@ func Login(a *app.App) http.HandlerFunc {
 
 		if err != nil {
 			if err == sql.ErrNoRows {
+				audit.Record(a.DB, r, audit.Entry{Action: audit.ActionLoginFailed},
+					map[string]interface{}{"username": creds.Username, "reason": "unknown user"})
 				app.RespondWithError(w, http.StatusUnauthorized, "Invalid username or password")
 				return
 			}
@@ -86,6 +102,8 @@ func Login(a *app.App) http.HandlerFunc {
 		}
 
 		if err := bcrypt.CompareHashAndPassword([]byte(storedCreds.Password), []byte(creds.Password)); err != nil {
+			audit.Record(a.DB, r, audit.Entry{Action: audit.ActionLoginFailed, TargetType: "user", TargetID: id},
+				map[string]interface{}{"username": creds.Username, "reason": "wrong password"})
 			app.RespondWithError(w, http.StatusUnauthorized, "Invalid username or password")
 			return
 		}
@@ -95,7 +113,76 @@ func Login(a *app.App) http.HandlerFunc {
 			app.RespondWithError(w, http.StatusInternalServerError, "Error generating token")
 			return
 		}
+		for _, action := range []string{audit.ActionLogin, audit.ActionTokenCreated} {
+			audit.Record(a.DB, r, audit.Entry{ActorID: id, ActorUsername: creds.Username, Action: action,
+				TargetType: "user", TargetID: id}, nil)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(UserResponse{ID: id, Username: creds.Username, Token: token})
 	}
 }
+
+// ChangePassword godoc
+// @Summary Change the current user's password
+// @Description Replace the authenticated user's password after verifying the current one
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param password body user.PasswordChange true "Current and new password"
+// @Success 204
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 401 {object} app.ErrorResponse
+// @Failure 422 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /me/password [put]
+func ChangePassword(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var change PasswordChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var storedPassword string
+		err := a.DB.QueryRow("SELECT password FROM users WHERE id=$1", claims.ID).Scan(&storedPassword)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusUnauthorized, "User not found")
+				return
+			}
+			app.RespondWithError(w, http.StatusInternalServerError, "Error fetching user")
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(storedPassword), []byte(change.CurrentPassword)); err != nil {
+			app.RespondWithError(w, http.StatusUnauthorized, "Current password is incorrect")
+			return
+		}
+
+		violations, err := a.PasswordPolicy.Check(claims.Username, change.NewPassword)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Error checking password")
+			return
+		}
+		if len(violations) > 0 {
+			app.RespondWithErrorDetails(w, http.StatusUnprocessableEntity, "Password does not meet policy", violations)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(change.NewPassword), 8)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Error hashing password")
+			return
+		}
+
+		if _, err := a.DB.Exec("UPDATE users SET password=$1 WHERE id=$2", string(hashedPassword), claims.ID); err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Error updating password")
+			return
+		}
+		audit.Record(a.DB, r, audit.Entry{Action: audit.ActionPasswordChanged, TargetType: "user", TargetID: claims.ID}, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -10,3 +10,8 @@ type UserResponse struct {
 	Username string `json:"username"`
 	Token    string `json:"token"`
 }
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
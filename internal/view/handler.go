@@ -0,0 +1,279 @@
+package view
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nihsioK/go-kanban/internal/app"
+	"github.com/nihsioK/go-kanban/internal/audit"
+	"github.com/nihsioK/go-kanban/internal/project"
+)
+
+// Create godoc
+// @Summary Save a project view
+// @Description Save a named set of project listing filters for the authenticated user
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param view body View true "View name, filters and sharing"
+// @Success 201 {object} View
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /views [post]
+func Create(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var v View
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+		if _, err := project.ParseListOptions(v.Filters.Values()); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		claims := r.Context().Value("claims").(*app.Claims)
+		v.UserID = claims.ID
+
+		filters, _ := json.Marshal(v.Filters)
+		err := a.DB.QueryRow(`INSERT INTO saved_views (user_id, name, filters, shared)
+			VALUES ($1,$2,$3,$4) RETURNING id, created_at, updated_at`,
+			v.UserID, v.Name, filters, v.Shared,
+		).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to create view")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+// Update godoc
+// @Summary Update a saved view
+// @Description Replace the name, filters and sharing of a view owned by the authenticated user
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path string true "View ID"
+// @Param view body View true "Updated view"
+// @Success 200 {object} View
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /views/{id} [put]
+func Update(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		var v View
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+		if _, err := project.ParseListOptions(v.Filters.Values()); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var owner string
+		if err := a.DB.QueryRow("SELECT user_id FROM saved_views WHERE id=$1", id).Scan(&owner); err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "View not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized")
+			return
+		}
+
+		filters, _ := json.Marshal(v.Filters)
+		err := scanView(a.DB.QueryRow(`UPDATE saved_views SET name=$1, filters=$2, shared=$3
+			WHERE id=$4 AND user_id=$5 RETURNING `+viewColumns,
+			v.Name, filters, v.Shared, id, claims.ID), &v)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Update failed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+// GetAll godoc
+// @Summary List saved views
+// @Description List the authenticated user's views and the views other users have shared
+// @Tags views
+// @Accept json
+// @Produce json
+// @Success 200 {array} View
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /views [get]
+func GetAll(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*app.Claims)
+		rows, err := a.DB.Query(`SELECT `+viewColumns+` FROM saved_views
+			WHERE user_id=$1 OR shared ORDER BY name, id`, claims.ID)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch views")
+			return
+		}
+		defer rows.Close()
+
+		views := []View{}
+		for rows.Next() {
+			var v View
+			if err := scanView(rows, &v); err != nil {
+				app.RespondWithError(w, http.StatusInternalServerError, "Scan error")
+				return
+			}
+			views = append(views, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(views)
+	}
+}
+
+// GetOne godoc
+// @Summary Get a saved view
+// @Description Retrieve a view owned by or shared with the authenticated user
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path string true "View ID"
+// @Success 200 {object} View
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /views/{id} [get]
+func GetOne(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		v, err := visibleView(a.DB, id, claims.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "View not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+// Delete godoc
+// @Summary Delete a saved view
+// @Description Delete a view owned by the authenticated user
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path string true "View ID"
+// @Success 204
+// @Failure 403 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /views/{id} [delete]
+func Delete(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		var owner string
+		err := a.DB.QueryRow("SELECT user_id FROM saved_views WHERE id=$1", id).Scan(&owner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "View not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Error checking ownership")
+			}
+			return
+		}
+		if owner != claims.ID {
+			app.RespondWithError(w, http.StatusForbidden, "Not authorized to delete")
+			return
+		}
+
+		err = app.WithTx(a.DB, func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM saved_views WHERE id=$1 AND user_id=$2", id, claims.ID); err != nil {
+				return err
+			}
+			return audit.RecordTx(tx, r, audit.Entry{Action: audit.ActionViewDeleted, TargetType: "view", TargetID: id}, nil)
+		})
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Delete failed")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Projects godoc
+// @Summary List the projects matching a saved view
+// @Description Run a view's filters against the authenticated user's projects. Shared views are always evaluated against the caller's own projects.
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path string true "View ID"
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Success 200 {object} project.ProjectPage
+// @Failure 400 {object} app.ErrorResponse
+// @Failure 404 {object} app.ErrorResponse
+// @Failure 500 {object} app.ErrorResponse
+// @Security BearerAuth
+// @Router /views/{id}/projects [get]
+func Projects(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		claims := r.Context().Value("claims").(*app.Claims)
+
+		v, err := visibleView(a.DB, id, claims.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.RespondWithError(w, http.StatusNotFound, "View not found")
+			} else {
+				app.RespondWithError(w, http.StatusInternalServerError, "Query error")
+			}
+			return
+		}
+
+		q := v.Filters.Values()
+		for _, key := range []string{"limit", "cursor"} {
+			if val := r.URL.Query().Get(key); val != "" {
+				q.Set(key, val)
+			}
+		}
+		opts, err := project.ParseListOptions(q)
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		projects, next, err := project.ListProjects(a.DB, claims.ID, opts)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch projects")
+			return
+		}
+
+		app.SetNextLink(w, r, next)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(project.ProjectPage{Data: projects, NextCursor: next})
+	}
+}
@@ -0,0 +1,54 @@
+package view
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+type View struct {
+	ID        string    `json:"id,omitempty"`
+	UserID    string    `json:"user,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Filters   Filters   `json:"filters"`
+	Shared    bool      `json:"shared"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Filters mirrors the query parameters accepted by GET /projects.
+type Filters struct {
+	Status          []string `json:"status,omitempty"`
+	Dependency      string   `json:"dependency,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	Query           string   `json:"q,omitempty"`
+	UpdatedWithin   string   `json:"updated_within,omitempty"`
+	IncludeArchived bool     `json:"include_archived,omitempty"`
+	Sort            string   `json:"sort,omitempty"`
+}
+
+func (f Filters) Values() url.Values {
+	q := url.Values{}
+	if len(f.Status) > 0 {
+		q.Set("status", strings.Join(f.Status, ","))
+	}
+	if f.Dependency != "" {
+		q.Set("dependency", f.Dependency)
+	}
+	if len(f.Tags) > 0 {
+		q.Set("tag", strings.Join(f.Tags, ","))
+	}
+	if f.Query != "" {
+		q.Set("q", f.Query)
+	}
+	if f.UpdatedWithin != "" {
+		q.Set("updated_within", f.UpdatedWithin)
+	}
+	if f.IncludeArchived {
+		q.Set("include_archived", "true")
+	}
+	if f.Sort != "" {
+		q.Set("sort", f.Sort)
+	}
+	return q
+}
@@ -0,0 +1,28 @@
+package view
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+const viewColumns = `id, user_id, name, filters, shared, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanView(s scanner, v *View) error {
+	var filters []byte
+	if err := s.Scan(&v.ID, &v.UserID, &v.Name, &filters, &v.Shared, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(filters, &v.Filters)
+}
+
+// visibleView loads a view if it belongs to the user or has been shared.
+func visibleView(db *sql.DB, id, userID string) (View, error) {
+	var v View
+	err := scanView(db.QueryRow(`SELECT `+viewColumns+` FROM saved_views
+		WHERE id=$1 AND (user_id=$2 OR shared)`, id, userID), &v)
+	return v, err
+}